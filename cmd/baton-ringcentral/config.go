@@ -1,6 +1,11 @@
 package main
 
 import (
+	"fmt"
+	"net/url"
+
+	"github.com/conductorone/baton-ringcentral/pkg/client"
+	"github.com/conductorone/baton-ringcentral/pkg/connector"
 	"github.com/conductorone/baton-sdk/pkg/field"
 	"github.com/spf13/viper"
 )
@@ -8,7 +13,21 @@ import (
 const (
 	ringCentralClientID     = "ringcentral-client-id"
 	ringCentralClientSecret = "ringcentral-client-secret"
+	ringCentralAuthMode     = "ringcentral-auth-mode"
 	ringCentralJWT          = "ringcentral-jwt"
+	ringCentralRefreshToken = "ringcentral-refresh-token"
+	ringCentralServerURL    = "ringcentral-server-url"
+	ringCentralTokenCache   = "ringcentral-token-cache-path"
+	excludeInactive         = "exclude-inactive-extensions"
+	userSyncMode            = "user-sync-mode"
+	protectedRoles          = "protected-roles"
+	allowUnsafeRevokes      = "allow-unsafe-role-revokes"
+	fallbackRoleID          = "fallback-role-id"
+	deprovisioningPolicy    = "user-deprovisioning-policy"
+	reclaimPhoneNumbers     = "reclaim-phone-numbers"
+
+	// provisioning is the flag defined by the SDK that enables the provisioning actions.
+	provisioning = "provisioning"
 )
 
 var (
@@ -24,10 +43,74 @@ var (
 		field.WithDescription("Client Secret of the Baton App for RingCentral"),
 	)
 
+	rcAuthModeField = field.StringField(
+		ringCentralAuthMode,
+		field.WithDescription("Authentication mode of the Baton App for RingCentral: jwt, client-credentials or refresh-token"),
+		field.WithDefaultValue(connector.AuthModeJWT),
+	)
+
 	rcJWTField = field.StringField(
 		ringCentralJWT,
-		field.WithRequired(true),
-		field.WithDescription("JWT of the admin user on RingCentral platform"),
+		field.WithDescription("JWT of the admin user on RingCentral platform, required by the jwt authentication mode"),
+	)
+
+	rcRefreshTokenField = field.StringField(
+		ringCentralRefreshToken,
+		field.WithDescription("Refresh token issued to the Baton App by the authorization code flow, required by the refresh-token authentication mode"),
+	)
+
+	rcServerURLField = field.StringField(
+		ringCentralServerURL,
+		field.WithDescription("URL of the RingCentral platform server, like https://platform.devtest.ringcentral.com for the developer sandbox"),
+		field.WithDefaultValue(client.DefaultServerURL),
+	)
+
+	rcTokenCacheField = field.StringField(
+		ringCentralTokenCache,
+		field.WithDescription(
+			"File to persist the session on, so it's reused by the following runs instead of being revoked at the end of each sync, required by the refresh-token authentication mode",
+		),
+	)
+
+	excludeInactiveField = field.BoolField(
+		excludeInactive,
+		field.WithDescription("Skip the extensions with 'Unassigned' or 'NotActivated' status while syncing users, along with their site and call queue grants"),
+		field.WithDefaultValue(false),
+	)
+
+	userSyncModeField = field.StringField(
+		userSyncMode,
+		field.WithDescription("API the users are read from: extension, or scim for the attributes of the HR systems like the department and the manager"),
+		field.WithDefaultValue(connector.UserSyncModeExtension),
+	)
+
+	protectedRolesField = field.StringSliceField(
+		protectedRoles,
+		field.WithDescription("IDs or names of the roles that are never revoked from the user the connector authenticates as, nor from their last enabled holder"),
+		field.WithDefaultValue(connector.DefaultProtectedRoles),
+	)
+
+	allowUnsafeRevokesField = field.BoolField(
+		allowUnsafeRevokes,
+		field.WithDescription("Allow the revokes of protected roles that could lock the connector out of the account"),
+		field.WithDefaultValue(false),
+	)
+
+	fallbackRoleIDField = field.StringField(
+		fallbackRoleID,
+		field.WithDescription("ID of the role assigned to the users whose only role is revoked, the default role of the account is used if it's not set"),
+	)
+
+	deprovisioningPolicyField = field.StringField(
+		deprovisioningPolicy,
+		field.WithDescription("What deprovisioning a user does on the platform: disable keeps the extension and its phone numbers, delete removes it"),
+		field.WithDefaultValue(connector.DeprovisioningPolicyDisable),
+	)
+
+	reclaimPhoneNumbersField = field.BoolField(
+		reclaimPhoneNumbers,
+		field.WithDescription("Move the phone numbers and devices of the deleted users to the inventory of the account, instead of removing them"),
+		field.WithDefaultValue(false),
 	)
 
 	// ConfigurationFields defines the external configuration required for the
@@ -36,7 +119,18 @@ var (
 	ConfigurationFields = []field.SchemaField{
 		rcClientIDField,
 		rcClientSecretField,
+		rcAuthModeField,
 		rcJWTField,
+		rcRefreshTokenField,
+		rcServerURLField,
+		rcTokenCacheField,
+		excludeInactiveField,
+		userSyncModeField,
+		protectedRolesField,
+		allowUnsafeRevokesField,
+		fallbackRoleIDField,
+		deprovisioningPolicyField,
+		reclaimPhoneNumbersField,
 	}
 )
 
@@ -44,6 +138,67 @@ var (
 // error if it isn't valid. Implementing this function is optional, it only
 // needs to perform extra validations that cannot be encoded with configuration
 // parameters.
-func ValidateConfig(_ *viper.Viper) error {
+func ValidateConfig(v *viper.Viper) error {
+	switch authMode := v.GetString(ringCentralAuthMode); authMode {
+	case connector.AuthModeJWT, "":
+		if v.GetString(ringCentralJWT) == "" {
+			return fmt.Errorf("%s is required by the %s authentication mode", ringCentralJWT, connector.AuthModeJWT)
+		}
+	case connector.AuthModeRefreshToken:
+		if v.GetString(ringCentralRefreshToken) == "" {
+			return fmt.Errorf("%s is required by the %s authentication mode", ringCentralRefreshToken, connector.AuthModeRefreshToken)
+		}
+		// The refresh token is rotated on every use, so the configured one only works once unless the rotated one is persisted.
+		if v.GetString(ringCentralTokenCache) == "" {
+			return fmt.Errorf("%s is required by the %s authentication mode", ringCentralTokenCache, connector.AuthModeRefreshToken)
+		}
+	case connector.AuthModeClientCredentials:
+	default:
+		return fmt.Errorf(
+			"invalid %s: '%s' must be one of %s, %s or %s",
+			ringCentralAuthMode,
+			authMode,
+			connector.AuthModeJWT,
+			connector.AuthModeClientCredentials,
+			connector.AuthModeRefreshToken,
+		)
+	}
+
+	switch mode := v.GetString(userSyncMode); mode {
+	case connector.UserSyncModeExtension, connector.UserSyncModeSCIM, "":
+	default:
+		return fmt.Errorf("invalid %s: '%s' must be either %s or %s", userSyncMode, mode, connector.UserSyncModeExtension, connector.UserSyncModeSCIM)
+	}
+
+	switch policy := v.GetString(deprovisioningPolicy); policy {
+	case connector.DeprovisioningPolicyDisable, connector.DeprovisioningPolicyDelete, "":
+	default:
+		return fmt.Errorf(
+			"invalid %s: '%s' must be either %s or %s",
+			deprovisioningPolicy,
+			policy,
+			connector.DeprovisioningPolicyDisable,
+			connector.DeprovisioningPolicyDelete,
+		)
+	}
+
+	serverURL := v.GetString(ringCentralServerURL)
+	if serverURL == "" {
+		return nil
+	}
+
+	parsedURL, err := url.Parse(serverURL)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", ringCentralServerURL, err)
+	}
+
+	if parsedURL.Scheme != "https" || parsedURL.Host == "" {
+		return fmt.Errorf("invalid %s: '%s' must be an absolute https URL, like %s", ringCentralServerURL, serverURL, client.DefaultServerURL)
+	}
+
+	if parsedURL.RawQuery != "" || parsedURL.Fragment != "" {
+		return fmt.Errorf("invalid %s: '%s' must not include a query or a fragment", ringCentralServerURL, serverURL)
+	}
+
 	return nil
 }
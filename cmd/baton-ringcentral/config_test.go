@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-ringcentral/pkg/connector"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestValidateConfig tests that the credentials required by each authentication mode are enforced, and that the server URL is an https URL.
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]string
+		isValid bool
+	}{
+		{
+			name:    "jwt",
+			values:  map[string]string{ringCentralAuthMode: connector.AuthModeJWT, ringCentralJWT: "jwt"},
+			isValid: true,
+		},
+		{
+			name:   "jwt without the jwt",
+			values: map[string]string{ringCentralAuthMode: connector.AuthModeJWT},
+		},
+		{
+			name:    "client credentials",
+			values:  map[string]string{ringCentralAuthMode: connector.AuthModeClientCredentials},
+			isValid: true,
+		},
+		{
+			name: "refresh token",
+			values: map[string]string{
+				ringCentralAuthMode:     connector.AuthModeRefreshToken,
+				ringCentralRefreshToken: "refresh",
+				ringCentralTokenCache:   "token.json",
+			},
+			isValid: true,
+		},
+		{
+			name:   "refresh token without the refresh token",
+			values: map[string]string{ringCentralAuthMode: connector.AuthModeRefreshToken, ringCentralTokenCache: "token.json"},
+		},
+		{
+			name:   "refresh token without the token cache",
+			values: map[string]string{ringCentralAuthMode: connector.AuthModeRefreshToken, ringCentralRefreshToken: "refresh"},
+		},
+		{
+			name:    "server url",
+			values:  map[string]string{ringCentralJWT: "jwt", ringCentralServerURL: "https://platform.devtest.ringcentral.com"},
+			isValid: true,
+		},
+		{
+			name:    "server url with the rest api path",
+			values:  map[string]string{ringCentralJWT: "jwt", ringCentralServerURL: "https://platform.devtest.ringcentral.com/restapi/"},
+			isValid: true,
+		},
+		{
+			name:   "http server url",
+			values: map[string]string{ringCentralJWT: "jwt", ringCentralServerURL: "http://platform.devtest.ringcentral.com"},
+		},
+		{
+			name:   "relative server url",
+			values: map[string]string{ringCentralJWT: "jwt", ringCentralServerURL: "platform.devtest.ringcentral.com"},
+		},
+		{
+			name:   "server url with a query",
+			values: map[string]string{ringCentralJWT: "jwt", ringCentralServerURL: "https://platform.devtest.ringcentral.com?env=sandbox"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			for key, value := range tt.values {
+				v.Set(key, value)
+			}
+
+			err := ValidateConfig(v)
+			if tt.isValid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
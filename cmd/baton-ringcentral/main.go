@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/conductorone/baton-ringcentral/pkg/connector"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/config"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/field"
@@ -44,21 +46,30 @@ func main() {
 
 func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, error) {
 	// Get the arguments from Viper
-	rcClientID := v.GetString(ringCentralClientID)
-	rcClientSecret := v.GetString(ringCentralClientSecret)
-	rcJWT := v.GetString(ringCentralJWT)
+	cfg := connector.Config{
+		ClientID:             v.GetString(ringCentralClientID),
+		ClientSecret:         v.GetString(ringCentralClientSecret),
+		AuthMode:             v.GetString(ringCentralAuthMode),
+		JWT:                  v.GetString(ringCentralJWT),
+		RefreshToken:         v.GetString(ringCentralRefreshToken),
+		ServerURL:            v.GetString(ringCentralServerURL),
+		TokenCachePath:       v.GetString(ringCentralTokenCache),
+		ExcludeInactive:      v.GetBool(excludeInactive),
+		UserSyncMode:         v.GetString(userSyncMode),
+		ProtectedRoles:       v.GetStringSlice(protectedRoles),
+		AllowUnsafeRevokes:   v.GetBool(allowUnsafeRevokes),
+		FallbackRoleID:       v.GetString(fallbackRoleID),
+		DeprovisioningPolicy: v.GetString(deprovisioningPolicy),
+		ReclaimPhoneNumbers:  v.GetBool(reclaimPhoneNumbers),
+		Provisioning:         v.GetBool(provisioning),
+	}
 
 	l := ctxzap.Extract(ctx)
 	if err := ValidateConfig(v); err != nil {
 		return nil, err
 	}
 
-	cb, err := connector.New(
-		ctx,
-		rcClientID,
-		rcClientSecret,
-		rcJWT,
-	)
+	cb, err := connector.New(ctx, cfg)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
@@ -69,5 +80,26 @@ func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, e
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
 	}
-	return con, nil
+	return &connectorServer{ConnectorServer: con, connector: cb}, nil
+}
+
+/*
+connectorServer closes the session of the connector on Cleanup, which the SDK only calls at the end of a sync.
+The sessions of runs that don't sync, like the one-shot grants and revokes, aren't revoked and expire on their own.
+The connector starts a new session on its next request.
+*/
+type connectorServer struct {
+	types.ConnectorServer
+	connector *connector.Connector
+}
+
+func (s *connectorServer) Cleanup(ctx context.Context, request *v2.ConnectorServiceCleanupRequest) (*v2.ConnectorServiceCleanupResponse, error) {
+	resp, err := s.ConnectorServer.Cleanup(ctx, request)
+
+	closeErr := s.connector.Close(ctx)
+	if closeErr != nil {
+		ctxzap.Extract(ctx).Warn("ringcentral-connector: error closing the session", zap.Error(closeErr))
+	}
+
+	return resp, errors.Join(err, closeErr)
 }
@@ -0,0 +1,280 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// tokenExpirationMargin is how long before its expiration an access token is considered expired,
+// so a token is never sent when it's about to be rejected by the platform.
+const tokenExpirationMargin = 2 * time.Minute
+
+const (
+	grantTypeJWT               = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeRefreshToken      = "refresh_token"
+)
+
+/*
+TokenSource is the OAuth grant the client uses to request access tokens, there's one implementation per grant type supported by the platform.
+Sources are only used while holding the token lock of the client, so they don't need to be safe for concurrent use.
+*/
+type TokenSource interface {
+	// GrantForm returns the form of the token request.
+	GrantForm() url.Values
+	// OnToken receives every token issued to the client, so the source can keep the credentials rotated by the platform.
+	OnToken(tokenResponse *TokenResponse)
+}
+
+// JWTTokenSource uses the JWT bearer grant, with a JWT generated by the user the connector acts as.
+type JWTTokenSource struct {
+	JWT string
+}
+
+func (s *JWTTokenSource) GrantForm() url.Values {
+	form := url.Values{}
+	form.Add("grant_type", grantTypeJWT)
+	form.Add("assertion", s.JWT)
+
+	return form
+}
+
+func (s *JWTTokenSource) OnToken(_ *TokenResponse) {}
+
+// ClientCredentialsTokenSource uses the client credentials grant, the app authenticates as itself with its client ID and secret.
+type ClientCredentialsTokenSource struct{}
+
+func (s *ClientCredentialsTokenSource) GrantForm() url.Values {
+	form := url.Values{}
+	form.Add("grant_type", grantTypeClientCredentials)
+
+	return form
+}
+
+func (s *ClientCredentialsTokenSource) OnToken(_ *TokenResponse) {}
+
+/*
+RefreshTokenSource uses a refresh token issued by the authorization code flow.
+The platform rotates the refresh token on every use, so the source always keeps the last one it was issued.
+*/
+type RefreshTokenSource struct {
+	RefreshToken string
+}
+
+func (s *RefreshTokenSource) GrantForm() url.Values {
+	return refreshTokenForm(s.RefreshToken)
+}
+
+func (s *RefreshTokenSource) OnToken(tokenResponse *TokenResponse) {
+	if tokenResponse.RefreshToken != "" {
+		s.RefreshToken = tokenResponse.RefreshToken
+	}
+}
+
+func refreshTokenForm(refreshToken string) url.Values {
+	form := url.Values{}
+	form.Add("grant_type", grantTypeRefreshToken)
+	form.Add("refresh_token", refreshToken)
+
+	return form
+}
+
+// canAuthenticate reports whether the client holds the credentials to request access tokens by itself.
+func (c *RingCentralClient) canAuthenticate() bool {
+	return c.Config.ClientID != "" && c.Config.ClientSecret != "" && c.Config.TokenSource != nil
+}
+
+/*
+getValidToken returns an access token that isn't about to expire, renewing it when needed.
+Access tokens provided through WithAccessToken are returned as they are, since the client can't renew them.
+*/
+func (c *RingCentralClient) getValidToken(ctx context.Context) (string, error) {
+	c.tokenMtx.Lock()
+	defer c.tokenMtx.Unlock()
+
+	if !c.canAuthenticate() {
+		return c.accessToken, nil
+	}
+
+	if c.hasValidAccessToken() {
+		return c.accessToken, nil
+	}
+
+	err := c.renewAccessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return c.accessToken, nil
+}
+
+/*
+GrantedScopes returns the app permissions (like ReadAccounts or RoleManagement) granted to the current access token.
+It returns nil when they're unknown, since tokens provided through WithAccessToken don't state their scope.
+*/
+func (c *RingCentralClient) GrantedScopes() []string {
+	c.tokenMtx.Lock()
+	defer c.tokenMtx.Unlock()
+
+	return slices.Clone(c.scopes)
+}
+
+// hasValidAccessToken reports whether the current access token isn't about to expire. The caller must hold tokenMtx.
+func (c *RingCentralClient) hasValidAccessToken() bool {
+	return c.accessToken != "" && time.Now().Add(tokenExpirationMargin).Before(c.accessTokenExpiresAt)
+}
+
+// invalidateToken discards the given access token, unless another request already replaced it.
+func (c *RingCentralClient) invalidateToken(token string) {
+	c.tokenMtx.Lock()
+	defer c.tokenMtx.Unlock()
+
+	if c.accessToken == token {
+		c.accessToken = ""
+	}
+}
+
+/*
+renewAccessToken requests a new access token using the refresh token when there's a valid one,
+otherwise (or if the refresh fails) the grant of the token source is requested again. The caller must hold tokenMtx.
+*/
+func (c *RingCentralClient) renewAccessToken(ctx context.Context) error {
+	l := ctxzap.Extract(ctx)
+
+	if c.refreshToken != "" && time.Now().Add(tokenExpirationMargin).Before(c.refreshTokenExpiresAt) {
+		tokenResponse, err := c.requestToken(ctx, refreshTokenForm(c.refreshToken))
+		if err == nil {
+			c.setToken(ctx, tokenResponse)
+			return nil
+		}
+
+		l.Warn("ringcentral-connector: error refreshing the access token, requesting a new one with the configured grant", zap.Error(err))
+	}
+
+	tokenResponse, err := c.requestToken(ctx, c.Config.TokenSource.GrantForm())
+	if err != nil {
+		return err
+	}
+
+	c.setToken(ctx, tokenResponse)
+
+	return nil
+}
+
+// setToken stores the tokens of the response along with their expiration time, persisting them on the token cache when it's enabled.
+// The caller must hold tokenMtx.
+func (c *RingCentralClient) setToken(ctx context.Context, tokenResponse *TokenResponse) {
+	now := time.Now()
+
+	c.accessToken = tokenResponse.AccessToken
+	c.accessTokenExpiresAt = now.Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+	c.refreshToken = tokenResponse.RefreshToken
+	c.refreshTokenExpiresAt = now.Add(time.Duration(tokenResponse.RefreshTokenExpiresIn) * time.Second)
+	c.scopes = strings.Fields(tokenResponse.Scope)
+
+	if c.Config.TokenSource != nil {
+		c.Config.TokenSource.OnToken(tokenResponse)
+	}
+
+	c.saveCachedToken(ctx)
+}
+
+// clearToken discards the session of the client, so the next request starts a new one. The caller must hold tokenMtx.
+func (c *RingCentralClient) clearToken() {
+	c.accessToken = ""
+	c.accessTokenExpiresAt = time.Time{}
+	c.refreshToken = ""
+	c.refreshTokenExpiresAt = time.Time{}
+	c.scopes = nil
+}
+
+/*
+Close ends the session of the client. When the token cache is enabled the session is kept for the following runs,
+otherwise its tokens are revoked on the platform. The client can still be used afterwards, starting a new session.
+Sessions of refresh tokens aren't revoked, since the client couldn't start a new one without the rotated refresh token,
+and neither are the access tokens provided through WithAccessToken, since they don't belong to the client.
+*/
+func (c *RingCentralClient) Close(ctx context.Context) error {
+	c.tokenMtx.Lock()
+	defer c.tokenMtx.Unlock()
+
+	if c.tokenCachePath != "" || !c.canAuthenticate() || c.accessToken == "" {
+		return nil
+	}
+
+	if _, ok := c.Config.TokenSource.(*RefreshTokenSource); ok {
+		return nil
+	}
+
+	err := c.revokeToken(ctx, c.accessToken)
+	c.clearToken()
+	if err != nil {
+		return fmt.Errorf("ringcentral-connector: failed to revoke the access token: %w", err)
+	}
+
+	return nil
+}
+
+// requestToken sends the given grant to the token endpoint, authenticating with the client ID and secret of the app.
+func (c *RingCentralClient) requestToken(ctx context.Context, form url.Values) (*TokenResponse, error) {
+	var tokenResponse TokenResponse
+
+	err := c.postOAuthForm(ctx, oauthURL, form, &tokenResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenResponse, nil
+}
+
+// revokeToken ends the session of the given token on the platform, revoking both its access and refresh tokens.
+func (c *RingCentralClient) revokeToken(ctx context.Context, token string) error {
+	form := url.Values{}
+	form.Add("token", token)
+
+	return c.postOAuthForm(ctx, revokeURL, form, nil)
+}
+
+// postOAuthForm sends the form to the given OAuth endpoint, authenticating with the client ID and secret of the app.
+func (c *RingCentralClient) postOAuthForm(ctx context.Context, endpoint string, form url.Values, res interface{}) error {
+	requestURL, err := url.JoinPath(c.baseURL, endpoint)
+	if err != nil {
+		return err
+	}
+
+	clientData := c.Config.ClientID + ":" + c.Config.ClientSecret
+	encodedClientData := base64.StdEncoding.EncodeToString([]byte(clientData))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Add("Authorization", "Basic "+encodedClientData)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if resp != nil && !isSuccessStatus(resp.StatusCode) {
+			return newAPIError(resp)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if res == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(res)
+}
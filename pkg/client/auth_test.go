@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+fakePlatform is a minimal token endpoint and extension list of the platform, that rejects the first access token it issues
+(or every token when rejectAll is set). The access tokens sent to the extension list are recorded.
+*/
+type fakePlatform struct {
+	mtx          sync.Mutex
+	issued       int
+	grants       []string
+	refreshToken string
+	revoked      []string
+	rejectAll    bool
+	requests     []string
+}
+
+func (p *fakePlatform) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case restAPIPath + oauthURL:
+		_ = r.ParseForm()
+		p.grants = append(p.grants, r.Form.Get("grant_type"))
+		p.issued++
+		p.refreshToken = fmt.Sprintf("refresh-%d", p.issued)
+		_ = json.NewEncoder(w).Encode(TokenResponse{
+			AccessToken:           fmt.Sprintf("token-%d", p.issued),
+			ExpiresIn:             3600,
+			RefreshToken:          p.refreshToken,
+			RefreshTokenExpiresIn: 604800,
+			Scope:                 "ReadAccounts RoleManagement",
+		})
+
+	case restAPIPath + revokeURL:
+		_ = r.ParseForm()
+		p.revoked = append(p.revoked, r.Form.Get("token"))
+
+	case restAPIPath + getExtensions:
+		p.requests = append(p.requests, r.Header.Get("Authorization"))
+		if p.rejectAll || r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"errorCode":"AGW-401","message":"Token not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"records":[{"id":1,"type":"User","status":"Enabled"}]}`))
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// TestClient_ReauthenticatesOnUnauthorized tests that a rejected access token is renewed using the refresh token, and the request retried.
+func TestClient_ReauthenticatesOnUnauthorized(t *testing.T) {
+	tests := []struct {
+		name   string
+		option Option
+		grant  string
+	}{
+		{name: "jwt", option: WithJWT("jwt"), grant: grantTypeJWT},
+		{name: "client credentials", option: WithClientCredentials(), grant: grantTypeClientCredentials},
+		{name: "refresh token", option: WithRefreshToken("refresh-0"), grant: grantTypeRefreshToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			platform := &fakePlatform{}
+			server := httptest.NewServer(platform)
+			defer server.Close()
+
+			c, err := New(context.Background(), WithBaseURL(server.URL), WithClientID("id"), WithClientSecret("secret"), tt.option)
+			require.NoError(t, err)
+			assert.Equal(t, "token-1", c.GetToken())
+			assert.Equal(t, []string{"ReadAccounts", "RoleManagement"}, c.GrantedScopes())
+
+			extensions, _, _, err := c.ListAllUsers(context.Background(), PageOptions{})
+			require.NoError(t, err)
+			assert.Len(t, extensions, 1)
+			assert.Equal(t, "token-2", c.GetToken())
+			assert.Equal(t, []string{tt.grant, grantTypeRefreshToken}, platform.grants)
+
+			// The token sources keep the refresh token rotated by the platform.
+			if source, ok := c.Config.TokenSource.(*RefreshTokenSource); ok {
+				assert.Equal(t, platform.refreshToken, source.RefreshToken)
+			}
+		})
+	}
+}
+
+// expireToken makes the current access token of the client expire after the given duration.
+func expireToken(c *RingCentralClient, after time.Duration) {
+	c.tokenMtx.Lock()
+	defer c.tokenMtx.Unlock()
+
+	c.accessTokenExpiresAt = time.Now().Add(after)
+}
+
+// TestClient_RefreshesBeforeExpiration tests that an access token about to expire is refreshed before being sent.
+func TestClient_RefreshesBeforeExpiration(t *testing.T) {
+	platform := &fakePlatform{}
+	server := httptest.NewServer(platform)
+	defer server.Close()
+
+	c, err := New(context.Background(), WithBaseURL(server.URL), WithClientID("id"), WithClientSecret("secret"), WithJWT("jwt"))
+	require.NoError(t, err)
+
+	expireToken(c, tokenExpirationMargin/2)
+
+	_, _, _, err = c.ListAllUsers(context.Background(), PageOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer token-2"}, platform.requests)
+	assert.Equal(t, []string{grantTypeJWT, grantTypeRefreshToken}, platform.grants)
+}
+
+// TestClient_RetriesOnceOnUnauthorized tests that a request is only retried once when the renewed access token is rejected too.
+func TestClient_RetriesOnceOnUnauthorized(t *testing.T) {
+	platform := &fakePlatform{rejectAll: true}
+	server := httptest.NewServer(platform)
+	defer server.Close()
+
+	c, err := New(context.Background(), WithBaseURL(server.URL), WithClientID("id"), WithClientSecret("secret"), WithJWT("jwt"))
+	require.NoError(t, err)
+
+	_, _, _, err = c.ListAllUsers(context.Background(), PageOptions{})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2"}, platform.requests)
+	assert.Equal(t, []string{grantTypeJWT, grantTypeRefreshToken}, platform.grants)
+}
+
+// TestClient_ConcurrentRenewal tests that concurrent requests with an expired access token share a single renewal.
+func TestClient_ConcurrentRenewal(t *testing.T) {
+	platform := &fakePlatform{}
+	server := httptest.NewServer(platform)
+	defer server.Close()
+
+	c, err := New(context.Background(), WithBaseURL(server.URL), WithClientID("id"), WithClientSecret("secret"), WithJWT("jwt"))
+	require.NoError(t, err)
+
+	expireToken(c, -time.Minute)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _, errs[i] = c.ListAllUsers(context.Background(), PageOptions{})
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, "token-2", c.GetToken())
+	assert.Equal(t, []string{grantTypeJWT, grantTypeRefreshToken}, platform.grants)
+	assert.NotContains(t, platform.requests, "Bearer token-1")
+}
+
+// TestClient_Close tests that the session is revoked on Close, unless it's kept for the following runs.
+func TestClient_Close(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		revoked []string
+	}{
+		{name: "jwt", options: []Option{WithJWT("jwt")}, revoked: []string{"token-1"}},
+		{name: "refresh token", options: []Option{WithRefreshToken("refresh-0")}},
+		{name: "token cache", options: []Option{WithJWT("jwt"), WithTokenCache(filepath.Join(t.TempDir(), "token.json"))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			platform := &fakePlatform{}
+			server := httptest.NewServer(platform)
+			defer server.Close()
+
+			options := append([]Option{WithBaseURL(server.URL), WithClientID("id"), WithClientSecret("secret")}, tt.options...)
+			c, err := New(context.Background(), options...)
+			require.NoError(t, err)
+
+			require.NoError(t, c.Close(context.Background()))
+			assert.Equal(t, tt.revoked, platform.revoked)
+		})
+	}
+}
+
+// TestClient_TokenCache tests that a cached session is reused by the following clients, as long as they share its configuration.
+func TestClient_TokenCache(t *testing.T) {
+	platform := &fakePlatform{}
+	server := httptest.NewServer(platform)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "token.json")
+	newClient := func(clientID string) *RingCentralClient {
+		c, err := New(context.Background(), WithBaseURL(server.URL), WithClientID(clientID), WithClientSecret("secret"), WithJWT("jwt"), WithTokenCache(path))
+		require.NoError(t, err)
+
+		return c
+	}
+
+	assert.Equal(t, "token-1", newClient("id").GetToken())
+	assert.Equal(t, "token-1", newClient("id").GetToken())
+	assert.Equal(t, "token-2", newClient("another-id").GetToken())
+	assert.Equal(t, []string{grantTypeJWT, grantTypeJWT}, platform.grants)
+}
@@ -2,39 +2,85 @@ package client
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	urlBase = "https://platform.ringcentral.com/restapi"
+	DefaultServerURL = "https://platform.ringcentral.com"
+	restAPIPath      = "/restapi"
 
-	oauthURL          = "/oauth/token"
-	getExtensions     = "/v1.0/account/~/extension"
-	getAvailableRoles = "/v1.0/account/~/user-role"
-	userRoles         = "/v1.0/account/~/extension/%s/assigned-role"
+	oauthURL                = "/oauth/token"
+	revokeURL               = "/oauth/revoke"
+	getAccount              = "/v1.0/account/~"
+	currentExtension        = "/v1.0/account/~/extension/~"
+	getExtensions           = "/v1.0/account/~/extension"
+	extensionByID           = "/v1.0/account/~/extension/%s"
+	getAvailableRoles       = "/v1.0/account/~/user-role"
+	getRole                 = "/v1.0/account/~/user-role/%s"
+	createRole              = "/v1.0/account/~/user-role"
+	deleteRole              = "/v1.0/account/~/user-role/%s"
+	getDefaultRole          = "/v1.0/account/~/user-role/default"
+	roleBulkAssign          = "/v1.0/account/~/user-role/%s/bulk-assign"
+	userRoles               = "/v1.0/account/~/extension/%s/assigned-role"
+	getCallQueues           = "/v1.0/account/~/call-queues"
+	callQueueMembers        = "/v1.0/account/~/call-queues/%s/members"
+	callQueueManagers       = "/v1.0/account/~/call-queues/%s/managers"
+	callQueueAssign         = "/v1.0/account/~/call-queues/%s/bulk-assign"
+	getSites                = "/v1.0/account/~/sites"
+	getPermissions          = "/v1.0/dictionary/permission"
+	getPermissionCategories = "/v1.0/dictionary/permission-category"
+	siteMembers             = "/v1.0/account/~/sites/%s/members"
 )
 
 type RingCentralClient struct {
-	Config      ClientConfig
-	client      *uhttp.BaseHttpClient
-	accessToken string
+	Config  ClientConfig
+	client  *uhttp.BaseHttpClient
+	baseURL string
+
+	// The token fields are guarded by tokenMtx since the client is shared by the syncers and the provisioning tasks.
+	tokenMtx              sync.Mutex
+	accessToken           string
+	accessTokenExpiresAt  time.Time
+	refreshToken          string
+	refreshTokenExpiresAt time.Time
+	scopes                []string
+	// extensionLocks holds a *sync.Mutex per extension ID, serializing the read-modify-write of the roles of each user.
+	extensionLocks sync.Map
+	// guardedRevokeMtx serializes the guarded revokes and deprovisionings across users, so two of them can't see each other as the remaining holder of a role.
+	guardedRevokeMtx sync.Mutex
+	// fallbackRoleID is the role assigned to the users whose only role is revoked, the default role of the account is requested if it's not set.
+	fallbackRoleMtx sync.Mutex
+	fallbackRoleID  string
+	// roleBulkAssignDisabled is set once the platform reports the bulk-assign operation of the roles as unavailable, since it isn't on every account.
+	roleBulkAssignDisabled atomic.Bool
+
+	// tokenCachePath is the file the session is persisted to, so it's reused across runs. Empty if the cache is disabled.
+	tokenCachePath string
 }
 
 type ClientConfig struct {
 	ClientID     string
 	ClientSecret string
-	JWT          string
+	// TokenSource is the OAuth grant used to request access tokens. Without it, the client only uses the token set with WithAccessToken.
+	TokenSource TokenSource
 }
 
 type Option func(c *RingCentralClient)
@@ -45,6 +91,19 @@ func WithAccessToken(accessToken string) Option {
 	}
 }
 
+// WithBaseURL sets the platform server the client talks to, like the developer sandbox (https://platform.devtest.ringcentral.com)
+// or a partner branded platform. The REST API path is appended to it. An empty URL keeps the default server.
+func WithBaseURL(serverURL string) Option {
+	return func(c *RingCentralClient) {
+		if serverURL == "" {
+			return
+		}
+
+		serverURL = strings.TrimSuffix(strings.TrimRight(serverURL, "/"), restAPIPath)
+		c.baseURL = serverURL + restAPIPath
+	}
+}
+
 func WithClientID(clientID string) Option {
 	return func(c *RingCentralClient) {
 		c.Config.ClientID = clientID
@@ -57,13 +116,50 @@ func WithClientSecret(clientSecret string) Option {
 	}
 }
 
+// WithJWT authenticates the client with the JWT bearer grant, using the JWT generated by the admin user.
 func WithJWT(jwt string) Option {
 	return func(c *RingCentralClient) {
-		c.Config.JWT = jwt
+		if jwt != "" {
+			c.Config.TokenSource = &JWTTokenSource{JWT: jwt}
+		}
+	}
+}
+
+// WithClientCredentials authenticates the client with the client credentials grant, available for private server apps.
+func WithClientCredentials() Option {
+	return func(c *RingCentralClient) {
+		c.Config.TokenSource = &ClientCredentialsTokenSource{}
+	}
+}
+
+// WithRefreshToken authenticates the client with a refresh token issued by the authorization code flow.
+func WithRefreshToken(refreshToken string) Option {
+	return func(c *RingCentralClient) {
+		if refreshToken != "" {
+			c.Config.TokenSource = &RefreshTokenSource{RefreshToken: refreshToken}
+		}
+	}
+}
+
+// WithTokenCache persists the session of the client on the given file, so the following runs reuse it while it's valid
+// instead of requesting new tokens. The file holds the tokens in plain text, readable only by its owner.
+func WithTokenCache(path string) Option {
+	return func(c *RingCentralClient) {
+		c.tokenCachePath = path
+	}
+}
+
+// WithFallbackRole sets the role assigned to the users whose only role is revoked, instead of the default role of the account.
+func WithFallbackRole(roleID string) Option {
+	return func(c *RingCentralClient) {
+		c.fallbackRoleID = roleID
 	}
 }
 
 func (c *RingCentralClient) GetToken() string {
+	c.tokenMtx.Lock()
+	defer c.tokenMtx.Unlock()
+
 	return c.accessToken
 }
 
@@ -78,63 +174,57 @@ func New(ctx context.Context, opts ...Option) (*RingCentralClient, error) {
 		return nil, err
 	}
 
-	rcClient := RingCentralClient{
-		client: cli,
+	rcClient := &RingCentralClient{
+		client:  cli,
+		baseURL: DefaultServerURL + restAPIPath,
 	}
 
 	for _, o := range opts {
-		o(&rcClient)
+		o(rcClient)
 	}
 
-	if rcClient.Config.ClientID != "" && rcClient.Config.ClientSecret != "" && rcClient.Config.JWT != "" {
-		newAccessToken, err := rcClient.requestAccessToken(ctx)
+	if rcClient.canAuthenticate() {
+		rcClient.tokenMtx.Lock()
+		rcClient.loadCachedToken(ctx)
+		if !rcClient.hasValidAccessToken() {
+			err = rcClient.renewAccessToken(ctx)
+		}
+		rcClient.tokenMtx.Unlock()
 		if err != nil {
 			return nil, err
 		}
-		rcClient.accessToken = newAccessToken
 	}
 
-	return &rcClient, nil
+	return rcClient, nil
 }
 
-func (c *RingCentralClient) requestAccessToken(ctx context.Context) (string, error) {
-	requestURL, err := url.JoinPath(urlBase, oauthURL)
-	if err != nil {
-		return "", err
-	}
-
-	clientData := c.Config.ClientID + ":" + c.Config.ClientSecret
-	encodedClientData := base64.StdEncoding.EncodeToString([]byte(clientData))
-
-	form := url.Values{}
-	form.Add("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
-	form.Add("assertion", c.Config.JWT)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, strings.NewReader(form.Encode()))
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Add("Authorization", "Basic "+encodedClientData)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var tokenResponse TokenResponse
-	err = json.NewDecoder(resp.Body).Decode(&tokenResponse)
-	if err != nil {
-		return "", err
-	}
+func (c *RingCentralClient) doRequest(
+	ctx context.Context,
+	method string,
+	endpointUrl string,
+	res interface{},
+	body interface{},
+	reqOpts ...ReqOpt,
+) (http.Header, error) {
+	return c.sendRequest(ctx, true, method, endpointUrl, res, body, reqOpts...)
+}
 
-	return tokenResponse.AccessToken, nil
+// doUncachedRequest works like doRequest, but GET requests skip the response cache. It's used when the response must reflect
+// the writes done by the client itself, like the reads of a read-modify-write.
+func (c *RingCentralClient) doUncachedRequest(
+	ctx context.Context,
+	method string,
+	endpointUrl string,
+	res interface{},
+	body interface{},
+	reqOpts ...ReqOpt,
+) (http.Header, error) {
+	return c.sendRequest(ctx, false, method, endpointUrl, res, body, reqOpts...)
 }
 
-func (c *RingCentralClient) doRequest(
+func (c *RingCentralClient) sendRequest(
 	ctx context.Context,
+	useCache bool,
 	method string,
 	endpointUrl string,
 	res interface{},
@@ -155,22 +245,60 @@ func (c *RingCentralClient) doRequest(
 		o(urlAddress)
 	}
 
-	req, err := c.client.NewRequest(
-		ctx,
-		method,
-		urlAddress,
-		uhttp.WithAcceptJSONHeader(),
-		uhttp.WithContentTypeJSONHeader(),
-		uhttp.WithHeader("Authorization", "Bearer "+c.GetToken()),
-		uhttp.WithJSONBody(body),
-	)
-	if err != nil {
-		return nil, err
-	}
+	// The request is retried once with a new access token when the platform rejects the current one,
+	// and up to maxRateLimitRetries times when it's throttled.
+	reauthenticated := false
+	throttledAttempts := 0
+	for {
+		token, err := c.getValidToken(ctx)
+		if err != nil {
+			return nil, err
+		}
 
-	resp, err = c.client.Do(req)
-	if err != nil {
-		return nil, err
+		req, err := c.client.NewRequest(
+			ctx,
+			method,
+			urlAddress,
+			uhttp.WithAcceptJSONHeader(),
+			uhttp.WithContentTypeJSONHeader(),
+			uhttp.WithHeader("Authorization", "Bearer "+token),
+			uhttp.WithJSONBody(body),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err = c.do(req, useCache)
+		if err == nil {
+			break
+		}
+
+		if resp == nil || isSuccessStatus(resp.StatusCode) {
+			return nil, err
+		}
+
+		// The rejected response is discarded before retrying, so its connection can be reused.
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && !reauthenticated && c.canAuthenticate():
+			_ = resp.Body.Close()
+			ctxzap.Extract(ctx).Debug("ringcentral-connector: access token rejected, requesting a new one")
+			c.invalidateToken(token)
+			reauthenticated = true
+
+		case resp.StatusCode == http.StatusTooManyRequests && throttledAttempts < maxRateLimitRetries:
+			_ = resp.Body.Close()
+			throttledAttempts++
+			err = waitForRateLimit(ctx, resp.Header, throttledAttempts)
+			if err != nil {
+				return nil, err
+			}
+
+		default:
+			// Non-2xx responses are reported with the error envelope of the platform instead of the generic error of the http client.
+			apiErr := newAPIError(resp)
+			_ = resp.Body.Close()
+			return nil, apiErr
+		}
 	}
 	defer resp.Body.Close()
 
@@ -189,145 +317,1084 @@ func (c *RingCentralClient) doRequest(
 	return resp.Header, nil
 }
 
+// do sends the request through the http client, skipping its response cache when useCache is false.
+func (c *RingCentralClient) do(req *http.Request, useCache bool) (*http.Response, error) {
+	if useCache {
+		return c.client.Do(req)
+	}
+
+	resp, err := c.client.HttpClient.Do(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if !isSuccessStatus(resp.StatusCode) {
+		return resp, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// GetAccount returns the account the credentials of the client belong to.
+func (c *RingCentralClient) GetAccount(ctx context.Context) (*Account, error) {
+	var account Account
+
+	queryUrl, err := url.JoinPath(c.baseURL, getAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.doRequest(ctx, http.MethodGet, queryUrl, &account, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+// GetCurrentExtension returns the extension the client is authenticated as.
+func (c *RingCentralClient) GetCurrentExtension(ctx context.Context) (*Extension, error) {
+	var extension Extension
+
+	queryUrl, err := url.JoinPath(c.baseURL, currentExtension)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.doRequest(ctx, http.MethodGet, queryUrl, &extension, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &extension, nil
+}
+
 /*
-ListAllUsers returns an array of users of the platform belonging to the company.
-Users withing the platform are named as 'Extension'.
+CreateExtension creates an extension, like the extension of a new user, returning it as created by the platform.
+The welcome email is sent by the platform when it's requested on the body, so the user sets up their own credentials.
 */
-func (c *RingCentralClient) ListAllUsers(ctx context.Context, pageOps PageOptions) ([]Extension, string, error) {
-	var response ExtensionResponse
+func (c *RingCentralClient) CreateExtension(ctx context.Context, body ExtensionCreateBody) (*Extension, error) {
+	var extension Extension
 
-	queryUrl, err := url.JoinPath(urlBase, getExtensions)
+	requestURL, err := url.JoinPath(c.baseURL, getExtensions)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	nextPage, err := c.getExtensionsListFromAPI(ctx, queryUrl, &response, WithPage(pageOps.Page), WithPageLimit(pageOps.PerPage))
+	_, err = c.doRequest(ctx, http.MethodPost, requestURL, &extension, body)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	return response.Records, nextPage, nil
+	return &extension, nil
 }
 
-func (c *RingCentralClient) ListAllAvailableRoles(ctx context.Context, pageOps PageOptions) ([]Role, string, error) {
-	var response RoleResponse
+// GetExtension returns the extension with the given ID. The response cache is skipped, so the status reflects the updates done by the client.
+func (c *RingCentralClient) GetExtension(ctx context.Context, extensionID string) (*Extension, error) {
+	var extension Extension
 
-	queryUrl, err := url.JoinPath(urlBase, getAvailableRoles)
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(extensionByID, extensionID))
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	nextPage, err := c.getRolesListFromAPI(ctx, queryUrl, &response, WithPage(pageOps.Page), WithPageLimit(pageOps.PerPage))
+	_, err = c.doUncachedRequest(ctx, http.MethodGet, queryUrl, &extension, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	return response.Records, nextPage, nil
+	return &extension, nil
 }
 
-func (c *RingCentralClient) GetUserAssignedRoles(ctx context.Context, userResource *v2.Resource) ([]UserRole, error) {
-	var res UserRoleResponse
-	queryUrl, err := url.JoinPath(urlBase, fmt.Sprintf(userRoles, userResource.Id.Resource))
+/*
+GetExtensionDetails returns the extension with the given ID through the response cache. It's meant for the syncs,
+which read the details of every listed extension, so the pages requested again (like on retries) don't request them again.
+*/
+func (c *RingCentralClient) GetExtensionDetails(ctx context.Context, extensionID string) (*Extension, error) {
+	var extension Extension
+
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(extensionByID, extensionID))
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = c.doRequest(ctx, http.MethodGet, queryUrl, &res, nil)
+	_, err = c.doRequest(ctx, http.MethodGet, queryUrl, &extension, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return res.Records, nil
+	return &extension, nil
 }
 
-func (c *RingCentralClient) getExtensionsListFromAPI(
-	ctx context.Context,
-	urlAddress string,
-	res *ExtensionResponse,
-	reqOpt ...ReqOpt,
-) (string, error) {
-	var pageToken string
+// UpdateExtensionStatus sets the status of the extension, like 'Enabled' or 'Disabled'.
+func (c *RingCentralClient) UpdateExtensionStatus(ctx context.Context, extensionID string, extensionStatus string) error {
+	body := ExtensionStatusUpdateBody{
+		Status: extensionStatus,
+	}
 
-	_, err := c.doRequest(ctx, http.MethodGet, urlAddress, &res, nil, reqOpt...)
+	requestURL, err := url.JoinPath(c.baseURL, fmt.Sprintf(extensionByID, extensionID))
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	if res.Paging.Page < res.Paging.TotalPages {
-		pageToken = strconv.Itoa(res.Paging.Page + 1)
+	_, err = c.doRequest(ctx, http.MethodPut, requestURL, nil, body)
+	if err != nil {
+		return err
 	}
 
-	return pageToken, nil
+	return nil
 }
 
-func (c *RingCentralClient) getRolesListFromAPI(ctx context.Context, urlAddress string, res *RoleResponse, reqOpt ...ReqOpt) (string, error) {
-	var pageToken string
+/*
+DeleteExtension deletes the extension with the given ID. When reclaimPhoneNumbers is set, its phone numbers and devices are moved
+to the inventory of the account so they can be assigned again, otherwise the platform removes them along with the extension.
+*/
+func (c *RingCentralClient) DeleteExtension(ctx context.Context, extensionID string, reclaimPhoneNumbers bool) error {
+	requestURL, err := url.JoinPath(c.baseURL, fmt.Sprintf(extensionByID, extensionID))
+	if err != nil {
+		return err
+	}
 
-	_, err := c.doRequest(ctx, http.MethodGet, urlAddress, &res, nil, reqOpt...)
+	reclaim := strconv.FormatBool(reclaimPhoneNumbers)
+	_, err = c.doRequest(
+		ctx,
+		http.MethodDelete,
+		requestURL,
+		nil,
+		nil,
+		WithQueryParam("savePhoneNumbers", reclaim),
+		WithQueryParam("savePhoneLines", reclaim),
+	)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	if res.Paging.Page < res.Paging.TotalPages {
-		pageToken = strconv.Itoa(res.Paging.Page + 1)
+	return nil
+}
+
+/*
+DeprovisionExtension runs deprovision, the disable or the delete of the extension. When checkDeprovision is set, it's called right before,
+within the same critical section as the guarded revokes of UpdateUserRoles (see RevokeCheck), so the deprovisionings and the guarded revokes
+of the client can't remove the last holder of a role together. Returning an error from the check aborts the deprovisioning.
+*/
+func (c *RingCentralClient) DeprovisionExtension(
+	ctx context.Context,
+	extensionID string,
+	checkDeprovision RevokeCheck,
+	deprovision func(ctx context.Context) error,
+) error {
+	// Same lock order as UpdateUserRoles: the guarded revokes first, then the extension.
+	if checkDeprovision != nil {
+		c.guardedRevokeMtx.Lock()
+		defer c.guardedRevokeMtx.Unlock()
+	}
+
+	unlock := c.lockExtension(extensionID)
+	defer unlock()
+
+	if checkDeprovision != nil {
+		err := checkDeprovision(ctx)
+		if err != nil {
+			return err
+		}
 	}
 
-	return pageToken, nil
+	return deprovision(ctx)
+}
+
+/*
+ListAllUsers returns an array of users of the platform belonging to the company.
+Users withing the platform are named as 'Extension'. Only the extension types that belong to a person are requested.
+*/
+func (c *RingCentralClient) ListAllUsers(ctx context.Context, pageOps PageOptions) ([]Extension, string, *v2.RateLimitDescription, error) {
+	return c.ListExtensions(ctx, UserExtensionTypes, pageOps)
 }
 
-// IdKeyValue is an auxiliary structure to build the body for the operation of update the roles list of a user.
-type IdKeyValue struct {
-	Id string `json:"id"`
+// ListExtensions returns an array of the extensions of the company filtered by the given extension types.
+func (c *RingCentralClient) ListExtensions(ctx context.Context, extensionTypes []string, pageOps PageOptions) ([]Extension, string, *v2.RateLimitDescription, error) {
+	var response ExtensionResponse
+
+	queryUrl, err := url.JoinPath(c.baseURL, getExtensions)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	nextPage, rateLimit, err := c.getExtensionsListFromAPI(
+		ctx,
+		queryUrl,
+		&response,
+		WithPage(pageOps.Page),
+		WithPageLimit(pageOps.PerPage),
+		WithQueryParamValues("type", extensionTypes...),
+	)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return response.Records, nextPage, rateLimit, nil
 }
 
 /*
-UpdateUserRoles receives the user resource (the principal of the Grant operation) and request the curren assigned roles for it.
-This function can be called on "revoking mode" or "granting mode". isRevoking sets the behavior.
-While granting: if the role that will be assigned isn't already part of the user roles, it sends the whole role list to the platform.
-While revoking: the list of assigned roles is sent to the platform by previously deleting the desired role.
+ListExtensionIDsByStatus returns the IDs of the extensions of the given types that have one of the given statuses, going through all the pages.
+It's read through the response cache, like the lists of the sync.
 */
-func (c *RingCentralClient) UpdateUserRoles(ctx context.Context, userResource *v2.Resource, roleID string, isRevoking bool) error {
-	// This variable is initialized like this and not with the "var roleIDs []IdKeyValue" semantic since it produces a bug when the array receives no elements.
-	roleIDs := []IdKeyValue{}
+func (c *RingCentralClient) ListExtensionIDsByStatus(ctx context.Context, extensionTypes []string, statuses []string) ([]string, error) {
+	var extensionIDs []string
 
-	// Request the list of the assigned roles of the user to be able to add the new one to that list.
-	assignedRoles, err := c.GetUserAssignedRoles(ctx, userResource)
+	queryUrl, err := url.JoinPath(c.baseURL, getExtensions)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, assignedRole := range assignedRoles {
-		arID := assignedRole.Id
+	page := 1
+	for {
+		var response ExtensionResponse
 
-		if arID == roleID {
-			if isRevoking {
-				// While revoking: If the current role ID equals the role that must be removed, it avoids adding it to the roles list.
-				continue
-			} else {
-				// While granting: If the current role ID equals the role should be added, an error is thrown, since the user already has that role.
-				return fmt.Errorf("the role with ID: '%s' is already assigned to the user with ID: '%s'", roleID, userResource.Id.Resource)
-			}
+		nextPage, _, err := c.getExtensionsListFromAPI(
+			ctx,
+			queryUrl,
+			&response,
+			WithPage(page),
+			WithPageLimit(ItemsPerPage),
+			WithQueryParamValues("type", extensionTypes...),
+			WithQueryParamValues("status", statuses...),
+		)
+		if err != nil {
+			return nil, err
 		}
 
-		roleIDs = append(roleIDs, IdKeyValue{Id: arID})
+		for _, extension := range response.Records {
+			extensionIDs = append(extensionIDs, strconv.FormatInt(extension.ID, 10))
+		}
+
+		if nextPage == "" {
+			return extensionIDs, nil
+		}
+
+		page, err = strconv.Atoi(nextPage)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *RingCentralClient) ListAllAvailableRoles(ctx context.Context, pageOps PageOptions) ([]Role, string, *v2.RateLimitDescription, error) {
+	var response RoleResponse
+
+	queryUrl, err := url.JoinPath(c.baseURL, getAvailableRoles)
+	if err != nil {
+		return nil, "", nil, err
 	}
 
-	if !isRevoking {
-		roleIDs = append(roleIDs, IdKeyValue{Id: roleID})
+	nextPage, rateLimit, err := c.getRolesListFromAPI(ctx, queryUrl, &response, WithPage(pageOps.Page), WithPageLimit(pageOps.PerPage))
+	if err != nil {
+		return nil, "", nil, err
 	}
 
-	body := map[string]interface{}{
-		"records": roleIDs,
+	return response.Records, nextPage, rateLimit, nil
+}
+
+// GetRole returns the role with the given ID, along with the permissions it holds.
+func (c *RingCentralClient) GetRole(ctx context.Context, roleID string) (*Role, error) {
+	var role Role
+
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(getRole, roleID))
+	if err != nil {
+		return nil, err
 	}
-	requestURL, err := url.JoinPath(urlBase, fmt.Sprintf(userRoles, userResource.Id.Resource))
+
+	_, err = c.doRequest(ctx, http.MethodGet, queryUrl, &role, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = c.doRequest(ctx, http.MethodPut, requestURL, nil, body)
+	return &role, nil
+}
+
+// GetDefaultRole returns the role the platform assigns to the new users of the account.
+func (c *RingCentralClient) GetDefaultRole(ctx context.Context) (*Role, error) {
+	var role Role
+
+	queryUrl, err := url.JoinPath(c.baseURL, getDefaultRole)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	_, err = c.doRequest(ctx, http.MethodGet, queryUrl, &role, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// getFallbackRoleID returns the role assigned to the users whose only role is revoked, requesting the default role of the account once if it's not set.
+func (c *RingCentralClient) getFallbackRoleID(ctx context.Context) (string, error) {
+	c.fallbackRoleMtx.Lock()
+	defer c.fallbackRoleMtx.Unlock()
+
+	if c.fallbackRoleID != "" {
+		return c.fallbackRoleID, nil
+	}
+
+	role, err := c.GetDefaultRole(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.fallbackRoleID = role.Id
+
+	return c.fallbackRoleID, nil
+}
+
+func (c *RingCentralClient) GetUserAssignedRoles(ctx context.Context, userResource *v2.Resource) ([]UserRole, *v2.RateLimitDescription, error) {
+	var res UserRoleResponse
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(userRoles, userResource.Id.Resource))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header, err := c.doRequest(ctx, http.MethodGet, queryUrl, &res, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res.Records, parseRateLimit(http.StatusOK, header), nil
+}
+
+// GetCurrentUserRoles returns the current roles of the user, skipping the response cache, for the checks that must see them as they are.
+func (c *RingCentralClient) GetCurrentUserRoles(ctx context.Context, extensionID string) ([]AssignedRoleRecord, error) {
+	return c.getAssignedRoleRecords(ctx, extensionID)
+}
+
+// getAssignedRoleRecords returns the current roles of the user as records of the roles list, skipping the response cache.
+func (c *RingCentralClient) getAssignedRoleRecords(ctx context.Context, extensionID string) ([]AssignedRoleRecord, error) {
+	var res UserRoleResponse
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(userRoles, extensionID))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.doUncachedRequest(ctx, http.MethodGet, queryUrl, &res, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// This variable is initialized like this and not with the "var records []AssignedRoleRecord" semantic since it produces a bug when the array receives no elements.
+	records := []AssignedRoleRecord{}
+	for _, assignedRole := range res.Records {
+		records = append(records, AssignedRoleRecord{
+			Id:             assignedRole.Id,
+			SiteRestricted: assignedRole.SiteRestricted,
+			Sites:          assignedRole.Sites,
+			AutoAssigned:   assignedRole.AutoAssigned,
+		})
+	}
+
+	return records, nil
+}
+
+// ListCallQueues returns an array of the call queues of the company.
+func (c *RingCentralClient) ListCallQueues(ctx context.Context, pageOps PageOptions) ([]CallQueue, string, *v2.RateLimitDescription, error) {
+	var response CallQueueResponse
+
+	queryUrl, err := url.JoinPath(c.baseURL, getCallQueues)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	header, err := c.doRequest(ctx, http.MethodGet, queryUrl, &response, nil, WithPage(pageOps.Page), WithPageLimit(pageOps.PerPage))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return response.Records, response.Paging.nextPageToken(), parseRateLimit(http.StatusOK, header), nil
+}
+
+// ListCallQueueMembers returns an array of the extensions that are members of the given call queue.
+func (c *RingCentralClient) ListCallQueueMembers(ctx context.Context, callQueueID string, pageOps PageOptions) ([]CallQueueMember, string, *v2.RateLimitDescription, error) {
+	var response CallQueueMemberResponse
+
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(callQueueMembers, callQueueID))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	header, err := c.doRequest(ctx, http.MethodGet, queryUrl, &response, nil, WithPage(pageOps.Page), WithPageLimit(pageOps.PerPage))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return response.Records, response.Paging.nextPageToken(), parseRateLimit(http.StatusOK, header), nil
+}
+
+// ListCallQueueManagers returns an array of the extensions allowed to manage the given call queue. The platform doesn't paginate this list.
+func (c *RingCentralClient) ListCallQueueManagers(ctx context.Context, callQueueID string) ([]CallQueueManager, *v2.RateLimitDescription, error) {
+	var response CallQueueManagerResponse
+
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(callQueueManagers, callQueueID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header, err := c.doRequest(ctx, http.MethodGet, queryUrl, &response, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return response.Records, parseRateLimit(http.StatusOK, header), nil
+}
+
+/*
+IsCallQueueMember walks through all the pages of members of the call queue looking for the given extension.
+The response cache is skipped, since the sync fills it with the same pages and the membership must reflect the updates done by the client.
+*/
+func (c *RingCentralClient) IsCallQueueMember(ctx context.Context, callQueueID string, extensionID string) (bool, error) {
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(callQueueMembers, callQueueID))
+	if err != nil {
+		return false, err
+	}
+
+	page := 1
+	for {
+		var response CallQueueMemberResponse
+
+		_, err = c.doUncachedRequest(ctx, http.MethodGet, queryUrl, &response, nil, WithPage(page), WithPageLimit(ItemsPerPage))
+		if err != nil {
+			return false, err
+		}
+
+		for _, member := range response.Records {
+			if member.ID == extensionID {
+				return true, nil
+			}
+		}
+
+		nextPage := response.Paging.nextPageToken()
+		if nextPage == "" {
+			return false, nil
+		}
+
+		page, err = strconv.Atoi(nextPage)
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+/*
+UpdateCallQueueMembers adds and removes members of a call queue in a single request using the bulk-assign operation.
+Extensions that aren't listed on any of both arrays keep their membership untouched.
+*/
+func (c *RingCentralClient) UpdateCallQueueMembers(ctx context.Context, callQueueID string, addedExtensionIDs []string, removedExtensionIDs []string) error {
+	body := CallQueueBulkAssignBody{
+		AddedExtensionIds:   addedExtensionIDs,
+		RemovedExtensionIds: removedExtensionIDs,
+	}
+
+	requestURL, err := url.JoinPath(c.baseURL, fmt.Sprintf(callQueueAssign, callQueueID))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doRequest(ctx, http.MethodPost, requestURL, nil, body)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListPermissions returns an array of the permissions of the platform, as listed on its permissions dictionary.
+func (c *RingCentralClient) ListPermissions(ctx context.Context, pageOps PageOptions) ([]Permission, string, *v2.RateLimitDescription, error) {
+	var response PermissionResponse
+
+	queryUrl, err := url.JoinPath(c.baseURL, getPermissions)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	header, err := c.doRequest(ctx, http.MethodGet, queryUrl, &response, nil, WithPage(pageOps.Page), WithPageLimit(pageOps.PerPage))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return response.Records, response.Paging.nextPageToken(), parseRateLimit(http.StatusOK, header), nil
+}
+
+// ListAllPermissionCategories returns every category of the permissions dictionary, going through all the pages.
+func (c *RingCentralClient) ListAllPermissionCategories(ctx context.Context) ([]PermissionCategory, error) {
+	var categories []PermissionCategory
+
+	queryUrl, err := url.JoinPath(c.baseURL, getPermissionCategories)
+	if err != nil {
+		return nil, err
+	}
+
+	page := 1
+	for {
+		var response PermissionCategoryResponse
+
+		_, err = c.doRequest(ctx, http.MethodGet, queryUrl, &response, nil, WithPage(page), WithPageLimit(ItemsPerPage))
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, response.Records...)
+
+		nextPage := response.Paging.nextPageToken()
+		if nextPage == "" {
+			return categories, nil
+		}
+
+		page, err = strconv.Atoi(nextPage)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ListSites returns an array of the sites of the company. Accounts without multi-site enabled only have the 'main-site'.
+func (c *RingCentralClient) ListSites(ctx context.Context, pageOps PageOptions) ([]Site, string, *v2.RateLimitDescription, error) {
+	var response SiteResponse
+
+	queryUrl, err := url.JoinPath(c.baseURL, getSites)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	header, err := c.doRequest(ctx, http.MethodGet, queryUrl, &response, nil, WithPage(pageOps.Page), WithPageLimit(pageOps.PerPage))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return response.Records, response.Paging.nextPageToken(), parseRateLimit(http.StatusOK, header), nil
+}
+
+// ListAllSites walks through all the pages of sites of the company.
+func (c *RingCentralClient) ListAllSites(ctx context.Context) ([]Site, error) {
+	var sites []Site
+
+	page := 1
+	for {
+		pageSites, nextPage, _, err := c.ListSites(ctx, PageOptions{Page: page, PerPage: ItemsPerPage})
+		if err != nil {
+			return nil, err
+		}
+
+		sites = append(sites, pageSites...)
+
+		if nextPage == "" {
+			return sites, nil
+		}
+
+		page, err = strconv.Atoi(nextPage)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ListSiteMembers returns an array of the extensions that belong to the given site.
+func (c *RingCentralClient) ListSiteMembers(ctx context.Context, siteID string, pageOps PageOptions) ([]SiteMember, string, *v2.RateLimitDescription, error) {
+	var response SiteMemberResponse
+
+	queryUrl, err := url.JoinPath(c.baseURL, fmt.Sprintf(siteMembers, siteID))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	header, err := c.doRequest(ctx, http.MethodGet, queryUrl, &response, nil, WithPage(pageOps.Page), WithPageLimit(pageOps.PerPage))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return response.Records, response.Paging.nextPageToken(), parseRateLimit(http.StatusOK, header), nil
+}
+
+func (c *RingCentralClient) getExtensionsListFromAPI(
+	ctx context.Context,
+	urlAddress string,
+	res *ExtensionResponse,
+	reqOpt ...ReqOpt,
+) (string, *v2.RateLimitDescription, error) {
+	var pageToken string
+
+	header, err := c.doRequest(ctx, http.MethodGet, urlAddress, &res, nil, reqOpt...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if res.Paging.Page < res.Paging.TotalPages {
+		pageToken = strconv.Itoa(res.Paging.Page + 1)
+	}
+
+	return pageToken, parseRateLimit(http.StatusOK, header), nil
+}
+
+func (c *RingCentralClient) getRolesListFromAPI(ctx context.Context, urlAddress string, res *RoleResponse, reqOpt ...ReqOpt) (string, *v2.RateLimitDescription, error) {
+	var pageToken string
+
+	header, err := c.doRequest(ctx, http.MethodGet, urlAddress, &res, nil, reqOpt...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if res.Paging.Page < res.Paging.TotalPages {
+		pageToken = strconv.Itoa(res.Paging.Page + 1)
+	}
+
+	return pageToken, parseRateLimit(http.StatusOK, header), nil
+}
+
+// AssignedRoleRecord is an auxiliary structure to build the body for the operation of update the roles list of a user.
+// The site scope of each role must be sent back, otherwise the role is assigned account wide.
+type AssignedRoleRecord struct {
+	Id             string          `json:"id"`
+	SiteRestricted bool            `json:"siteRestricted,omitempty"`
+	Sites          []SiteReference `json:"sites,omitempty"`
+	// AutoAssigned isn't part of the body, it tells the roles assigned by the platform itself apart.
+	AutoAssigned bool `json:"-"`
+}
+
+// RoleUpdate is the result of UpdateUserRoles.
+type RoleUpdate struct {
+	// Updated is false when the roles of the user already matched the request, so nothing was sent to the platform.
+	Updated bool
+	// FallbackRoleID is the role assigned to the user in place of the revoked one, when it was the only role of the user.
+	FallbackRoleID string
+}
+
+/*
+RevokeCheck is called by UpdateUserRoles right before writing a revoke, within the same critical section as the write,
+so it can refuse the revoke based on the current holders of the role. Returning an error aborts the revoke.
+*/
+type RevokeCheck func(ctx context.Context) error
+
+// maxRoleUpdateAttempts is the number of times the roles list of a user is written before giving up on a concurrent writer.
+const maxRoleUpdateAttempts = 3
+
+/*
+UpdateUserRoles receives the user resource (the principal of the Grant operation) and request the curren assigned roles for it.
+This function can be called on "revoking mode" or "granting mode". isRevoking sets the behavior.
+When siteID is set, the operation only affects the assignment of the role restricted to that site, otherwise the account wide assignment is affected.
+While granting: if the role that will be assigned isn't already part of the user roles, it sends the whole role list to the platform.
+While revoking: the list of assigned roles is sent to the platform by previously deleting the desired role (or the desired site from its scope).
+The site scope of the rest of the roles is preserved.
+When checkRevoke is set, it's called before every write of a revoke. The guarded revokes are serialized with each other, and with the rest
+of the updates of the roles of the user, so the check sees the holders of the role as they are when the write happens.
+It returns a not updated result when the roles of the user already match the request (the role is already granted or already revoked),
+in which case the list isn't sent to the platform. Since every user must hold a role, revoking the only role of a user assigns the fallback
+role in its place (see WithFallbackRole), which is reported on the result. Roles auto-assigned by the platform can't be revoked.
+
+Account wide assignments are written with the bulk-assign operation of the role when possible, which only touches that role (see writeRoleUpdate).
+Since the whole list is replaced on the rest of the writes, the updates of the roles of a user are serialized within the client, and the list is read
+again after the write: if another writer (like an admin, or another instance of the connector) replaced it in between and the update
+got lost, the update is applied again over the new list.
+*/
+func (c *RingCentralClient) UpdateUserRoles(
+	ctx context.Context,
+	userResource *v2.Resource,
+	roleID string,
+	siteID string,
+	isRevoking bool,
+	checkRevoke RevokeCheck,
+) (RoleUpdate, error) {
+	l := ctxzap.Extract(ctx)
+	extensionID := userResource.Id.Resource
+
+	// The guarded revokes are locked before the extension, which is the order every caller takes both locks in.
+	if isRevoking && checkRevoke != nil {
+		c.guardedRevokeMtx.Lock()
+		defer c.guardedRevokeMtx.Unlock()
+	}
+
+	unlock := c.lockExtension(extensionID)
+	defer unlock()
+
+	requestURL, err := url.JoinPath(c.baseURL, fmt.Sprintf(userRoles, extensionID))
+	if err != nil {
+		return RoleUpdate{}, err
+	}
+
+	// Request the list of the assigned roles of the user to be able to add the new one to that list.
+	assignedRoles, err := c.getAssignedRoleRecords(ctx, extensionID)
+	if err != nil {
+		return RoleUpdate{}, err
+	}
+
+	for attempt := 1; attempt <= maxRoleUpdateAttempts; attempt++ {
+		records, changed := applyRoleUpdate(assignedRoles, roleID, siteID, isRevoking)
+		if !changed {
+			return RoleUpdate{}, nil
+		}
+
+		// Auto-assigned roles are assigned again by the platform right after being revoked.
+		if isRevoking && slices.ContainsFunc(assignedRoles, func(record AssignedRoleRecord) bool {
+			return record.Id == roleID && record.AutoAssigned
+		}) {
+			return RoleUpdate{}, status.Errorf(
+				codes.FailedPrecondition,
+				"ringcentral-connector: the role with ID: '%s' is auto-assigned to the user with ID: '%s' by the platform, it can't be revoked",
+				roleID,
+				extensionID,
+			)
+		}
+
+		// Every user must hold a role, so revoking the only one assigns the fallback role in its place.
+		fallbackRoleID := ""
+		if len(records) == 0 {
+			fallbackRoleID, err = c.getFallbackRoleID(ctx)
+			if err != nil {
+				return RoleUpdate{}, err
+			}
+
+			if fallbackRoleID == roleID {
+				return RoleUpdate{}, status.Errorf(
+					codes.FailedPrecondition,
+					"ringcentral-connector: the role with ID: '%s' can't be revoked from the user with ID: '%s', it's the only role of the user and the fallback role",
+					roleID,
+					extensionID,
+				)
+			}
+
+			records = []AssignedRoleRecord{{Id: fallbackRoleID}}
+		}
+
+		if isRevoking && checkRevoke != nil {
+			err = checkRevoke(ctx)
+			if err != nil {
+				return RoleUpdate{}, err
+			}
+		}
+
+		err = c.writeRoleUpdate(ctx, requestURL, extensionID, roleID, siteID, isRevoking, fallbackRoleID == "", assignedRoles, records)
+		if err != nil {
+			return RoleUpdate{}, err
+		}
+
+		// Verify that the update was kept, the read is also the starting point of the next attempt if it wasn't.
+		assignedRoles, err = c.getAssignedRoleRecords(ctx, extensionID)
+		if err != nil {
+			return RoleUpdate{}, err
+		}
+
+		if _, pending := applyRoleUpdate(assignedRoles, roleID, siteID, isRevoking); !pending {
+			return RoleUpdate{Updated: true, FallbackRoleID: fallbackRoleID}, nil
+		}
+
+		l.Warn("ringcentral-connector: the roles of the user changed while updating them, retrying",
+			zap.String("extension_id", extensionID),
+			zap.String("role_id", roleID),
+			zap.String("site_id", siteID),
+			zap.Int("attempt", attempt))
+	}
+
+	return RoleUpdate{}, status.Errorf(
+		codes.Aborted,
+		"ringcentral-connector: the roles of the user with ID: '%s' kept changing while updating the role with ID: '%s'",
+		extensionID,
+		roleID,
+	)
+}
+
+/*
+writeRoleUpdate sends the update of the role to the platform. Account wide assignments of roles the user either doesn't have or has account wide
+are sent through the bulk-assign operation of the role, which doesn't touch the rest of the roles of the user. The site restricted assignments,
+the revokes that assign the fallback role (when canBulkAssign is false), and every assignment once the platform rejected the bulk-assign
+operation, are written by replacing the whole roles list with the given records.
+*/
+func (c *RingCentralClient) writeRoleUpdate(
+	ctx context.Context,
+	requestURL string,
+	extensionID string,
+	roleID string,
+	siteID string,
+	isRevoking bool,
+	canBulkAssign bool,
+	assignedRoles []AssignedRoleRecord,
+	records []AssignedRoleRecord,
+) error {
+	siteRestricted := slices.ContainsFunc(assignedRoles, func(record AssignedRoleRecord) bool {
+		return record.Id == roleID && record.SiteRestricted
+	})
+
+	if canBulkAssign && siteID == "" && !siteRestricted && !c.roleBulkAssignDisabled.Load() {
+		var addedExtensionIDs, removedExtensionIDs []string
+		if isRevoking {
+			removedExtensionIDs = []string{extensionID}
+		} else {
+			addedExtensionIDs = []string{extensionID}
+		}
+
+		err := c.BulkAssignRole(ctx, roleID, addedExtensionIDs, removedExtensionIDs)
+		if err == nil {
+			return nil
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !apiErr.IsUnsupportedOperation() {
+			return err
+		}
+
+		ctxzap.Extract(ctx).Warn("ringcentral-connector: the bulk-assign operation of the roles isn't available, replacing the roles list instead",
+			zap.String("role_id", roleID),
+			zap.Error(err))
+		c.roleBulkAssignDisabled.Store(true)
+	}
+
+	body := map[string]interface{}{
+		"records": records,
+	}
+
+	_, err := c.doRequest(ctx, http.MethodPut, requestURL, nil, body)
+
+	return err
+}
+
+/*
+BulkAssignRole assigns the role to, and unassigns it from, several extensions in a single request using the bulk-assign operation.
+The assignments are account wide, and the rest of the roles of the extensions are left untouched.
+*/
+func (c *RingCentralClient) BulkAssignRole(ctx context.Context, roleID string, addedExtensionIDs []string, removedExtensionIDs []string) error {
+	body := RoleBulkAssignBody{
+		AddedExtensionIds:   addedExtensionIDs,
+		RemovedExtensionIds: removedExtensionIDs,
+	}
+
+	requestURL, err := url.JoinPath(c.baseURL, fmt.Sprintf(roleBulkAssign, roleID))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doRequest(ctx, http.MethodPost, requestURL, nil, body)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+/*
+CreateRole creates a custom role holding the given permissions.
+Only the IDs of the permissions are sent, since the references returned by the platform also carry their URIs.
+*/
+func (c *RingCentralClient) CreateRole(ctx context.Context, displayName string, description string, template Role) (*Role, error) {
+	var role Role
+
+	body := RoleCreateBody{
+		DisplayName:    displayName,
+		Description:    description,
+		Scope:          template.Scope,
+		SiteCompatible: template.SiteCompatible,
+	}
+	for _, permission := range template.Permissions {
+		body.Permissions = append(body.Permissions, PermissionReference{ID: permission.ID})
+	}
+
+	requestURL, err := url.JoinPath(c.baseURL, createRole)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.doRequest(ctx, http.MethodPost, requestURL, &role, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// DeleteRole deletes the custom role with the given ID. The platform refuses to delete the predefined roles.
+func (c *RingCentralClient) DeleteRole(ctx context.Context, roleID string) error {
+	requestURL, err := url.JoinPath(c.baseURL, fmt.Sprintf(deleteRole, roleID))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doRequest(ctx, http.MethodDelete, requestURL, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// HasOtherRoleHolder reports whether an enabled user other than the given extension has the role assigned account wide.
+func (c *RingCentralClient) HasOtherRoleHolder(ctx context.Context, roleID string, excludedExtensionID string) (bool, error) {
+	return c.findRoleHolder(
+		ctx,
+		func(user Extension) bool {
+			return strconv.FormatInt(user.ID, 10) != excludedExtensionID && user.Status == ExtensionStatusEnabled
+		},
+		func(record AssignedRoleRecord) bool {
+			return record.Id == roleID && !record.SiteRestricted
+		},
+	)
+}
+
+// HasRoleAssignees reports whether any user has the role assigned, either account wide or restricted to a site, whatever their status.
+func (c *RingCentralClient) HasRoleAssignees(ctx context.Context, roleID string) (bool, error) {
+	return c.findRoleHolder(
+		ctx,
+		func(_ Extension) bool {
+			return true
+		},
+		func(record AssignedRoleRecord) bool {
+			return record.Id == roleID
+		},
+	)
+}
+
+/*
+findRoleHolder goes through the users of the company accepted by isCandidate requesting the roles of each one,
+until the first one with a role assignment accepted by isMatch is found. The platform has no way to list the holders of a role.
+The response cache is skipped, since the users and their roles must reflect the updates done after the sync.
+*/
+func (c *RingCentralClient) findRoleHolder(ctx context.Context, isCandidate func(Extension) bool, isMatch func(AssignedRoleRecord) bool) (bool, error) {
+	queryUrl, err := url.JoinPath(c.baseURL, getExtensions)
+	if err != nil {
+		return false, err
+	}
+
+	page := 1
+	for {
+		var response ExtensionResponse
+
+		_, err = c.doUncachedRequest(
+			ctx,
+			http.MethodGet,
+			queryUrl,
+			&response,
+			nil,
+			WithPage(page),
+			WithPageLimit(ItemsPerPage),
+			WithQueryParamValues("type", UserExtensionTypes...),
+		)
+		if err != nil {
+			return false, err
+		}
+
+		for _, user := range response.Records {
+			if !isCandidate(user) {
+				continue
+			}
+
+			assignedRoles, err := c.getAssignedRoleRecords(ctx, strconv.FormatInt(user.ID, 10))
+			if err != nil {
+				return false, err
+			}
+
+			if slices.ContainsFunc(assignedRoles, isMatch) {
+				return true, nil
+			}
+		}
+
+		nextPage := response.Paging.nextPageToken()
+		if nextPage == "" {
+			return false, nil
+		}
+
+		page, err = strconv.Atoi(nextPage)
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// lockExtension locks the roles of the given extension, returning the function that unlocks them.
+func (c *RingCentralClient) lockExtension(extensionID string) func() {
+	mtx, _ := c.extensionLocks.LoadOrStore(extensionID, &sync.Mutex{})
+	extensionMtx := mtx.(*sync.Mutex)
+	extensionMtx.Lock()
+
+	return extensionMtx.Unlock
+}
+
+/*
+applyRoleUpdate applies a grant or a revoke of the role over the list of the assigned roles of a user.
+It returns the resulting list, and whether it differs from the given one.
+*/
+func applyRoleUpdate(assignedRoles []AssignedRoleRecord, roleID string, siteID string, isRevoking bool) ([]AssignedRoleRecord, bool) {
+	// This variable is initialized like this and not with the "var records []AssignedRoleRecord" semantic since it produces a bug when the array receives no elements.
+	records := []AssignedRoleRecord{}
+	found := false
+	changed := false
+
+	for _, record := range assignedRoles {
+		if record.Id != roleID {
+			records = append(records, record)
+			continue
+		}
+
+		found = true
+		record, keep, recordChanged := updateAssignedRoleRecord(record, siteID, isRevoking)
+		changed = changed || recordChanged
+
+		if keep {
+			records = append(records, record)
+		}
+	}
+
+	if !isRevoking && !found {
+		record := AssignedRoleRecord{Id: roleID}
+		if siteID != "" {
+			record.SiteRestricted = true
+			record.Sites = []SiteReference{{ID: siteID}}
+		}
+
+		records = append(records, record)
+		changed = true
+	}
+
+	return records, changed
+}
+
+/*
+updateAssignedRoleRecord applies a grant or a revoke over the record of a role that is already assigned to the user.
+It returns the updated record, whether the record must still be part of the roles list, and whether the record was changed at all.
+*/
+func updateAssignedRoleRecord(record AssignedRoleRecord, siteID string, isRevoking bool) (AssignedRoleRecord, bool, bool) {
+	siteIndex := slices.IndexFunc(record.Sites, func(site SiteReference) bool {
+		return site.ID == siteID
+	})
+
+	switch {
+	case isRevoking && siteID == "":
+		// While revoking the account wide assignment: the role is removed from the roles list, unless it's only assigned for some sites.
+		if record.SiteRestricted {
+			return record, true, false
+		}
+
+		return record, false, true
+
+	case isRevoking:
+		// While revoking a site assignment: the site is removed from the scope, the role is removed once no site is left.
+		if !record.SiteRestricted || siteIndex < 0 {
+			return record, true, false
+		}
+
+		record.Sites = slices.Delete(slices.Clone(record.Sites), siteIndex, siteIndex+1)
+
+		return record, len(record.Sites) > 0, true
+
+	case !record.SiteRestricted:
+		// While granting: an account wide assignment already covers every site.
+		return record, true, false
+
+	case siteID == "":
+		// While granting the account wide assignment: the site restriction of the role is dropped.
+		record.SiteRestricted = false
+		record.Sites = nil
+
+		return record, true, true
+
+	case siteIndex >= 0:
+		return record, true, false
+
+	default:
+		record.Sites = append(slices.Clone(record.Sites), SiteReference{ID: siteID})
+
+		return record, true, true
+	}
 }
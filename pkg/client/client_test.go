@@ -0,0 +1,535 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestUpdateAssignedRoleRecord tests how grants and revokes are applied over a role that is already assigned to a user.
+func TestUpdateAssignedRoleRecord(t *testing.T) {
+	accountWide := AssignedRoleRecord{Id: "role"}
+	berlin := AssignedRoleRecord{Id: "role", SiteRestricted: true, Sites: []SiteReference{{ID: "berlin"}}}
+
+	tests := []struct {
+		name       string
+		record     AssignedRoleRecord
+		siteID     string
+		isRevoking bool
+		expected   AssignedRoleRecord
+		keep       bool
+		changed    bool
+	}{
+		{name: "grant account wide over account wide", record: accountWide, expected: accountWide, keep: true},
+		{name: "grant site over account wide", record: accountWide, siteID: "paris", expected: accountWide, keep: true},
+		{name: "grant site over same site", record: berlin, siteID: "berlin", expected: berlin, keep: true},
+		{
+			name:     "grant account wide over site",
+			record:   berlin,
+			expected: accountWide,
+			keep:     true,
+			changed:  true,
+		},
+		{
+			name:     "grant another site",
+			record:   berlin,
+			siteID:   "paris",
+			expected: AssignedRoleRecord{Id: "role", SiteRestricted: true, Sites: []SiteReference{{ID: "berlin"}, {ID: "paris"}}},
+			keep:     true,
+			changed:  true,
+		},
+		{name: "revoke account wide", record: accountWide, isRevoking: true, expected: accountWide, changed: true},
+		{name: "revoke account wide over site", record: berlin, isRevoking: true, expected: berlin, keep: true},
+		{
+			name:       "revoke last site",
+			record:     berlin,
+			siteID:     "berlin",
+			isRevoking: true,
+			expected:   AssignedRoleRecord{Id: "role", SiteRestricted: true, Sites: []SiteReference{}},
+			changed:    true,
+		},
+		{name: "revoke missing site", record: berlin, siteID: "paris", isRevoking: true, expected: berlin, keep: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, keep, changed := updateAssignedRoleRecord(tt.record, tt.siteID, tt.isRevoking)
+			assert.Equal(t, tt.keep, keep)
+			assert.Equal(t, tt.changed, changed)
+			assert.Equal(t, tt.expected, record)
+		})
+	}
+
+	// The sites of the original record must not be modified, since they belong to the response of the platform.
+	assert.Equal(t, []SiteReference{{ID: "berlin"}}, berlin.Sites)
+}
+
+// TestNewAPIError tests the parsing of the error envelope of the platform and its mapping into gRPC codes.
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		expected   codes.Code
+	}{
+		{
+			name:       "resource not found",
+			statusCode: http.StatusNotFound,
+			body:       `{"errorCode":"CMN-102","message":"Resource for parameter [extensionId] is not found","errors":[{"errorCode":"CMN-102","parameterName":"extensionId"}]}`,
+			expected:   codes.NotFound,
+		},
+		{
+			name:       "missing permission",
+			statusCode: http.StatusForbidden,
+			body:       `{"errorCode":"InsufficientPermissions","message":"In order to call this API endpoint, application needs to have [RoleManagement] permission"}`,
+			expected:   codes.PermissionDenied,
+		},
+		{
+			name:       "rate limited",
+			statusCode: http.StatusTooManyRequests,
+			body:       `{"errorCode":"CMN-301","message":"Request rate exceeded"}`,
+			expected:   codes.Unavailable,
+		},
+		{
+			name:       "expired token",
+			statusCode: http.StatusUnauthorized,
+			body:       `{"errorCode":"AGW-401","message":"Authorization header is not specified"}`,
+			expected:   codes.Unauthenticated,
+		},
+		{
+			name:       "oauth error",
+			statusCode: http.StatusBadRequest,
+			body:       `{"error":"invalid_grant","error_description":"Token is expired","errors":[{"errorCode":"OAU-211","message":"Token is expired"}]}`,
+			expected:   codes.Unauthenticated,
+		},
+		{
+			name:       "not a json body",
+			statusCode: http.StatusBadGateway,
+			body:       "<html>Bad Gateway</html>",
+			expected:   codes.Unavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.statusCode,
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+
+			err := newAPIError(resp)
+			assert.Equal(t, tt.expected, status.Code(err))
+			assert.Equal(t, tt.statusCode, err.StatusCode)
+		})
+	}
+}
+
+// TestParseRateLimit tests the parsing of the rate limit headers of the platform.
+func TestParseRateLimit(t *testing.T) {
+	header := http.Header{}
+	assert.Nil(t, parseRateLimit(http.StatusOK, header))
+
+	header.Set(rateLimitGroupHeader, "Heavy")
+	header.Set(rateLimitLimitHeader, "10")
+	header.Set(rateLimitRemainingHeader, "4")
+	header.Set(rateLimitWindowHeader, "60")
+
+	rateLimit := parseRateLimit(http.StatusOK, header)
+	require.NotNil(t, rateLimit)
+	assert.Equal(t, int64(10), rateLimit.Limit)
+	assert.Equal(t, int64(4), rateLimit.Remaining)
+	assert.Equal(t, "STATUS_OK", rateLimit.Status.String())
+
+	header.Set(retryAfterHeader, "30")
+	rateLimit = parseRateLimit(http.StatusTooManyRequests, header)
+	require.NotNil(t, rateLimit)
+	assert.Equal(t, int64(0), rateLimit.Remaining)
+	assert.Equal(t, "STATUS_OVERLIMIT", rateLimit.Status.String())
+	assert.Equal(t, 30*time.Second, retryAfter(header))
+}
+
+// TestWithBaseURL tests that the server URL is normalized into the base URL of the REST API, whatever the form it's given in.
+func TestWithBaseURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		serverURL string
+		baseURL   string
+	}{
+		{name: "default", baseURL: DefaultServerURL + restAPIPath},
+		{name: "server", serverURL: "https://platform.devtest.ringcentral.com", baseURL: "https://platform.devtest.ringcentral.com/restapi"},
+		{name: "trailing slash", serverURL: "https://platform.devtest.ringcentral.com/", baseURL: "https://platform.devtest.ringcentral.com/restapi"},
+		{name: "rest api path", serverURL: "https://platform.devtest.ringcentral.com/restapi", baseURL: "https://platform.devtest.ringcentral.com/restapi"},
+		{name: "rest api path with trailing slash", serverURL: "https://platform.devtest.ringcentral.com/restapi/", baseURL: "https://platform.devtest.ringcentral.com/restapi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(context.Background(), WithBaseURL(tt.serverURL), WithAccessToken("token"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.baseURL, c.baseURL)
+		})
+	}
+}
+
+// TestClient_RetriesThrottledRequests tests that throttled requests are retried after the time stated by the platform, up to maxRateLimitRetries times.
+func TestClient_RetriesThrottledRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		throttled int
+		requests  int
+		succeeds  bool
+	}{
+		{name: "throttled once", throttled: 1, requests: 2, succeeds: true},
+		{name: "always throttled", throttled: -1, requests: maxRateLimitRetries + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				mtx      sync.Mutex
+				requests int
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mtx.Lock()
+				defer mtx.Unlock()
+
+				requests++
+				w.Header().Set("Content-Type", "application/json")
+				if tt.throttled < 0 || requests <= tt.throttled {
+					w.Header().Set(rateLimitGroupHeader, "Light")
+					w.Header().Set(rateLimitLimitHeader, "50")
+					w.Header().Set(retryAfterHeader, "1")
+					w.WriteHeader(http.StatusTooManyRequests)
+					_, _ = w.Write([]byte(`{"errorCode":"CMN-301","message":"Request rate exceeded"}`))
+					return
+				}
+
+				_, _ = w.Write([]byte(`{"id":1,"status":"Confirmed"}`))
+			}))
+			defer server.Close()
+
+			c, err := New(context.Background(), WithBaseURL(server.URL), WithAccessToken("token"))
+			require.NoError(t, err)
+
+			account, err := c.GetAccount(context.Background())
+			assert.Equal(t, tt.requests, requests)
+			if tt.succeeds {
+				require.NoError(t, err)
+				assert.Equal(t, int64(1), account.ID)
+				return
+			}
+
+			var apiErr *APIError
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+			assert.Equal(t, codes.Unavailable, status.Code(err))
+			require.NotNil(t, apiErr.RateLimit)
+			assert.Equal(t, v2.RateLimitDescription_STATUS_OVERLIMIT, apiErr.RateLimit.Status)
+		})
+	}
+}
+
+/*
+fakeUserRoles serves the assigned roles of a user, replacing them on PUT and updating them on bulk-assign, and the 'default' role as the default role of the account. onPut is called after each PUT,
+to simulate other writers. The bulk-assign operation is rejected with bulkAssignErrorCode when bulkAssignStatus is set.
+*/
+type fakeUserRoles struct {
+	mtx                 sync.Mutex
+	records             []AssignedRoleRecord
+	puts                int
+	bulkAssigns         int
+	bulkAssignStatus    int
+	bulkAssignErrorCode string
+	onPut               func(f *fakeUserRoles)
+}
+
+func (f *fakeUserRoles) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if r.Method == http.MethodPost {
+		f.bulkAssigns++
+		if f.bulkAssignStatus != 0 {
+			w.WriteHeader(f.bulkAssignStatus)
+			_ = json.NewEncoder(w).Encode(APIError{ErrorCode: f.bulkAssignErrorCode, Message: "Bulk assign is rejected"})
+			return
+		}
+
+		var body RoleBulkAssignBody
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		roleID := path.Base(path.Dir(r.URL.Path))
+		f.records = slices.DeleteFunc(f.records, func(record AssignedRoleRecord) bool {
+			return record.Id == roleID
+		})
+		if len(body.AddedExtensionIds) > 0 {
+			f.records = append(f.records, AssignedRoleRecord{Id: roleID})
+		}
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var body struct {
+			Records []AssignedRoleRecord `json:"records"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		f.records = body.Records
+		f.puts++
+		if f.onPut != nil {
+			f.onPut(f)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if strings.HasSuffix(r.URL.Path, getDefaultRole) {
+		_ = json.NewEncoder(w).Encode(Role{Id: "default"})
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"records": f.records})
+}
+
+func (f *fakeUserRoles) roleIDs() []string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	var ids []string
+	for _, record := range f.records {
+		ids = append(ids, record.Id)
+	}
+
+	return ids
+}
+
+var testUser = &v2.Resource{Id: &v2.ResourceId{ResourceType: "user", Resource: "1"}}
+
+// TestUpdateUserRoles tests that the roles list is only sent to the platform when the roles of the user change.
+func TestUpdateUserRoles(t *testing.T) {
+	tests := []struct {
+		name       string
+		roleID     string
+		siteID     string
+		isRevoking bool
+		updated    bool
+		bulkAssign bool
+	}{
+		{name: "grant assigned role", roleID: "admin"},
+		{name: "grant assigned site", roleID: "manager", siteID: "berlin"},
+		{name: "revoke missing role", roleID: "auditor", isRevoking: true},
+		{name: "revoke missing site", roleID: "manager", siteID: "paris", isRevoking: true},
+		{name: "grant new role", roleID: "auditor", updated: true, bulkAssign: true},
+		{name: "revoke assigned role", roleID: "admin", isRevoking: true, updated: true, bulkAssign: true},
+		{name: "grant new site", roleID: "manager", siteID: "paris", updated: true},
+		{name: "grant account wide over site", roleID: "manager", updated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles := &fakeUserRoles{records: []AssignedRoleRecord{
+				{Id: "admin"},
+				{Id: "manager", SiteRestricted: true, Sites: []SiteReference{{ID: "berlin"}}},
+			}}
+			server := httptest.NewServer(roles)
+			defer server.Close()
+
+			c, err := New(context.Background(), WithBaseURL(server.URL), WithAccessToken("token"))
+			require.NoError(t, err)
+
+			roleUpdate, err := c.UpdateUserRoles(context.Background(), testUser, tt.roleID, tt.siteID, tt.isRevoking, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.updated, roleUpdate.Updated)
+			assert.Empty(t, roleUpdate.FallbackRoleID)
+			assert.Equal(t, tt.updated && tt.bulkAssign, roles.bulkAssigns == 1)
+			assert.Equal(t, tt.updated && !tt.bulkAssign, roles.puts == 1)
+		})
+	}
+}
+
+// TestUpdateUserRoles_Concurrent tests that concurrent grants for the same user don't overwrite each other.
+func TestUpdateUserRoles_Concurrent(t *testing.T) {
+	roles := &fakeUserRoles{records: []AssignedRoleRecord{}}
+	server := httptest.NewServer(roles)
+	defer server.Close()
+
+	c, err := New(context.Background(), WithBaseURL(server.URL), WithAccessToken("token"))
+	require.NoError(t, err)
+
+	roleIDs := []string{"admin", "auditor", "manager", "operator", "supervisor"}
+
+	var wg sync.WaitGroup
+	for _, roleID := range roleIDs {
+		wg.Add(1)
+		go func(roleID string) {
+			defer wg.Done()
+			_, err := c.UpdateUserRoles(context.Background(), testUser, roleID, "", false, nil)
+			assert.NoError(t, err)
+		}(roleID)
+	}
+	wg.Wait()
+
+	assert.ElementsMatch(t, roleIDs, roles.roleIDs())
+}
+
+/*
+TestUpdateUserRoles_BulkAssignRejected tests that the roles list is replaced once the platform reports the bulk-assign operation as unavailable,
+while the rest of the rejections, like a missing permission, are returned without disabling it.
+*/
+func TestUpdateUserRoles_BulkAssignRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		errorCode string
+		disabled  bool
+	}{
+		{name: "feature not available", status: http.StatusForbidden, errorCode: errorCodeFeatureNotAvailable, disabled: true},
+		{name: "method not allowed", status: http.StatusMethodNotAllowed, disabled: true},
+		{name: "missing permission", status: http.StatusForbidden, errorCode: "InsufficientPermissions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles := &fakeUserRoles{records: []AssignedRoleRecord{{Id: "admin"}}, bulkAssignStatus: tt.status, bulkAssignErrorCode: tt.errorCode}
+			server := httptest.NewServer(roles)
+			defer server.Close()
+
+			c, err := New(context.Background(), WithBaseURL(server.URL), WithAccessToken("token"))
+			require.NoError(t, err)
+
+			if !tt.disabled {
+				_, err := c.UpdateUserRoles(context.Background(), testUser, "auditor", "", false, nil)
+				assert.Equal(t, codes.PermissionDenied, status.Code(err))
+				assert.False(t, c.roleBulkAssignDisabled.Load())
+				assert.Equal(t, 1, roles.bulkAssigns)
+				assert.Zero(t, roles.puts)
+				return
+			}
+
+			for _, roleID := range []string{"auditor", "operator"} {
+				roleUpdate, err := c.UpdateUserRoles(context.Background(), testUser, roleID, "", false, nil)
+				require.NoError(t, err)
+				assert.True(t, roleUpdate.Updated)
+			}
+
+			assert.True(t, c.roleBulkAssignDisabled.Load())
+			assert.Equal(t, 1, roles.bulkAssigns)
+			assert.Equal(t, 2, roles.puts)
+			assert.ElementsMatch(t, []string{"admin", "auditor", "operator"}, roles.roleIDs())
+		})
+	}
+}
+
+// TestUpdateUserRoles_ConcurrentWriter tests that an update of the roles list overwritten by another writer is applied again.
+func TestUpdateUserRoles_ConcurrentWriter(t *testing.T) {
+	roles := &fakeUserRoles{records: []AssignedRoleRecord{{Id: "admin"}}}
+
+	// Another writer that read the list before the first write replaces it right after it, adding its own role.
+	roles.onPut = func(f *fakeUserRoles) {
+		f.records = []AssignedRoleRecord{{Id: "admin"}, {Id: "operator"}}
+		f.onPut = nil
+	}
+
+	server := httptest.NewServer(roles)
+	defer server.Close()
+
+	c, err := New(context.Background(), WithBaseURL(server.URL), WithAccessToken("token"))
+	require.NoError(t, err)
+
+	roleUpdate, err := c.UpdateUserRoles(context.Background(), testUser, "manager", "berlin", false, nil)
+	require.NoError(t, err)
+	assert.True(t, roleUpdate.Updated)
+	assert.Equal(t, 2, roles.puts)
+	assert.ElementsMatch(t, []string{"admin", "manager", "operator"}, roles.roleIDs())
+}
+
+// TestUpdateUserRoles_Fallback tests that revoking the only role of a user assigns the fallback role in its place.
+func TestUpdateUserRoles_Fallback(t *testing.T) {
+	tests := []struct {
+		name           string
+		options        []Option
+		records        []AssignedRoleRecord
+		roleID         string
+		siteID         string
+		fallbackRoleID string
+		fails          bool
+	}{
+		{name: "default role", records: []AssignedRoleRecord{{Id: "admin"}}, roleID: "admin", fallbackRoleID: "default"},
+		{name: "configured role", options: []Option{WithFallbackRole("auditor")}, records: []AssignedRoleRecord{{Id: "admin"}}, roleID: "admin", fallbackRoleID: "auditor"},
+		{
+			name:           "last site",
+			records:        []AssignedRoleRecord{{Id: "manager", SiteRestricted: true, Sites: []SiteReference{{ID: "berlin"}}}},
+			roleID:         "manager",
+			siteID:         "berlin",
+			fallbackRoleID: "default",
+		},
+		{name: "other roles left", records: []AssignedRoleRecord{{Id: "admin"}, {Id: "auditor"}}, roleID: "admin"},
+		{name: "fallback role itself", records: []AssignedRoleRecord{{Id: "default"}}, roleID: "default", fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles := &fakeUserRoles{records: tt.records}
+			server := httptest.NewServer(roles)
+			defer server.Close()
+
+			options := append([]Option{WithBaseURL(server.URL), WithAccessToken("token")}, tt.options...)
+			c, err := New(context.Background(), options...)
+			require.NoError(t, err)
+
+			roleUpdate, err := c.UpdateUserRoles(context.Background(), testUser, tt.roleID, tt.siteID, true, nil)
+			if tt.fails {
+				assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+				assert.Equal(t, 0, roles.puts+roles.bulkAssigns)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, roleUpdate.Updated)
+			assert.Equal(t, tt.fallbackRoleID, roleUpdate.FallbackRoleID)
+			assert.NotContains(t, roles.roleIDs(), tt.roleID)
+			if tt.fallbackRoleID != "" {
+				assert.Equal(t, []string{tt.fallbackRoleID}, roles.roleIDs())
+				assert.Equal(t, 1, roles.puts)
+			}
+		})
+	}
+}
+
+// TestUpdateUserRoles_AutoAssigned tests that the roles auto-assigned by the platform aren't revoked.
+func TestUpdateUserRoles_AutoAssigned(t *testing.T) {
+	puts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			puts++
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"records":[{"id":"admin"},{"id":"standard","autoAssigned":true}]}`))
+	}))
+	defer server.Close()
+
+	c, err := New(context.Background(), WithBaseURL(server.URL), WithAccessToken("token"))
+	require.NoError(t, err)
+
+	_, err = c.UpdateUserRoles(context.Background(), testUser, "standard", "", true, nil)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, 0, puts)
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// apiErrorCodes maps the error codes of the platform into gRPC codes, so the callers can tell retriable, permission and not found failures apart.
+var apiErrorCodes = map[string]codes.Code{
+	"CMN-101":                 codes.InvalidArgument,  // Parameter value is invalid.
+	"CMN-102":                 codes.NotFound,         // Resource for parameter is not found.
+	"CMN-104":                 codes.NotFound,         // Resource is not found.
+	"CMN-301":                 codes.Unavailable,      // Request rate exceeded.
+	"CMN-401":                 codes.PermissionDenied, // The user doesn't have the permission required by the endpoint.
+	"CMN-408":                 codes.PermissionDenied, // The app doesn't have the permission required by the endpoint.
+	"InsufficientPermissions": codes.PermissionDenied,
+	"AGW-401":                 codes.Unauthenticated, // The access token is missing or expired.
+	"AGW-402":                 codes.Unauthenticated, // The access token is invalid.
+	"AGW-403":                 codes.PermissionDenied,
+	"AGW-404":                 codes.NotFound,
+	"OAU-149":                 codes.Unauthenticated,  // The JWT is invalid or expired.
+	"OAU-211":                 codes.Unauthenticated,  // The refresh token is invalid or expired.
+	"OAU-251":                 codes.PermissionDenied, // The app isn't allowed to use the requested grant type.
+}
+
+// errorCodeFeatureNotAvailable is returned when the plan of the account doesn't include the feature behind an endpoint.
+const errorCodeFeatureNotAvailable = "FeatureNotAvailable"
+
+// APIErrorDetail is one of the errors listed in the error envelope of the platform.
+type APIErrorDetail struct {
+	ErrorCode     string `json:"errorCode,omitempty"`
+	Message       string `json:"message,omitempty"`
+	ParameterName string `json:"parameterName,omitempty"`
+}
+
+/*
+APIError represents a non-2xx response of the platform.
+The REST API returns the errorCode/message/errors envelope, while the OAuth endpoints return error/error_description along with the errors list.
+*/
+type APIError struct {
+	StatusCode       int                      `json:"-"`
+	RateLimit        *v2.RateLimitDescription `json:"-"`
+	ErrorCode        string                   `json:"errorCode,omitempty"`
+	Message          string                   `json:"message,omitempty"`
+	Errors           []APIErrorDetail         `json:"errors,omitempty"`
+	OAuthError       string                   `json:"error,omitempty"`
+	OAuthDescription string                   `json:"error_description,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	var details []string
+	for _, detail := range e.Errors {
+		if detail.ParameterName != "" {
+			details = append(details, fmt.Sprintf("%s (%s): %s", detail.ErrorCode, detail.ParameterName, detail.Message))
+			continue
+		}
+		details = append(details, fmt.Sprintf("%s: %s", detail.ErrorCode, detail.Message))
+	}
+
+	message := e.Message
+	if message == "" {
+		message = e.OAuthDescription
+	}
+
+	errorCode := e.ErrorCode
+	if errorCode == "" {
+		errorCode = e.OAuthError
+	}
+
+	msg := fmt.Sprintf("ringcentral-connector: request failed with status %d", e.StatusCode)
+	if errorCode != "" {
+		msg += fmt.Sprintf(" [%s]", errorCode)
+	}
+	if message != "" {
+		msg += ": " + message
+	}
+	if len(details) > 0 {
+		msg += " (" + strings.Join(details, "; ") + ")"
+	}
+
+	return msg
+}
+
+// HasErrorCode reports whether the envelope, or any of the listed errors, carries the given error code of the platform.
+func (e *APIError) HasErrorCode(errorCode string) bool {
+	if e.ErrorCode == errorCode {
+		return true
+	}
+
+	for _, detail := range e.Errors {
+		if detail.ErrorCode == errorCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+IsUnsupportedOperation reports whether the platform rejected the request because the operation isn't available on the account,
+rather than because of the permissions of the caller.
+*/
+func (e *APIError) IsUnsupportedOperation() bool {
+	return e.StatusCode == http.StatusMethodNotAllowed || (e.StatusCode == http.StatusForbidden && e.HasErrorCode(errorCodeFeatureNotAvailable))
+}
+
+// Code returns the gRPC code for the error, based on the error codes of the platform and, as a fallback, on the HTTP status.
+func (e *APIError) Code() codes.Code {
+	if code, ok := apiErrorCodes[e.ErrorCode]; ok {
+		return code
+	}
+
+	for _, detail := range e.Errors {
+		if code, ok := apiErrorCodes[detail.ErrorCode]; ok {
+			return code
+		}
+	}
+
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusTooManyRequests:
+		return codes.Unavailable
+	}
+
+	if e.StatusCode >= http.StatusInternalServerError {
+		return codes.Unavailable
+	}
+
+	return codes.Unknown
+}
+
+// GRPCStatus allows status.FromError and status.Code to read the code of the error.
+// The rate limit description is attached as a detail, so throttled tasks get retried once the limit resets.
+func (e *APIError) GRPCStatus() *status.Status {
+	st := status.New(e.Code(), e.Error())
+	if e.RateLimit == nil {
+		return st
+	}
+
+	stWithDetails, err := st.WithDetails(e.RateLimit)
+	if err != nil {
+		return st
+	}
+
+	return stWithDetails
+}
+
+// newAPIError builds an APIError from a non-2xx response. The body is parsed on a best effort basis, since gateways may not return JSON.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RateLimit:  parseRateLimit(resp.StatusCode, resp.Header),
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || len(body) == 0 {
+		return apiErr
+	}
+
+	if json.Unmarshal(body, apiErr) != nil {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+
+	return apiErr
+}
+
+func isSuccessStatus(statusCode int) bool {
+	return statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices
+}
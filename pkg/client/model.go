@@ -1,11 +1,30 @@
 package client
 
+import "strconv"
+
 type TokenResponse struct {
-	AccessToken string `json:"access_token"`
-	TokenType   string `json:"token_type"`
-	ExpiresIn   int    `json:"expires_in"`
+	AccessToken           string `json:"access_token"`
+	TokenType             string `json:"token_type"`
+	ExpiresIn             int    `json:"expires_in"`
+	RefreshToken          string `json:"refresh_token,omitempty"`
+	RefreshTokenExpiresIn int    `json:"refresh_token_expires_in,omitempty"`
+	Scope                 string `json:"scope,omitempty"`
+	OwnerID               string `json:"owner_id,omitempty"`
+}
+
+// Account Response Structures -->
+
+// Account is the company the credentials of the connector belong to.
+type Account struct {
+	URI         string `json:"uri,omitempty"`
+	ID          int64  `json:"id,omitempty"`
+	MainNumber  string `json:"mainNumber,omitempty"`
+	Status      string `json:"status,omitempty"`
+	SignupState string `json:"signupState,omitempty"`
 }
 
+// <-- Account Response Structures
+
 // Generic structures -->
 
 type BasicResponse struct {
@@ -29,6 +48,15 @@ type NavPage struct {
 	URI string `json:"uri,omitempty"`
 }
 
+// nextPageToken returns the number of the following page as a token, or an empty string if the current page is the last one.
+func (p Paging) nextPageToken() string {
+	if p.Page < p.TotalPages {
+		return strconv.Itoa(p.Page + 1)
+	}
+
+	return ""
+}
+
 // <-- Generic structures
 
 // Extension Response Structures -->
@@ -38,20 +66,96 @@ type ExtensionResponse struct {
 	Records []Extension `json:"records,omitempty"`
 }
 
+/*
+Extension is an extension of the company. The list of extensions only carries a summary of each one, the site, the cost center,
+the regional settings, the creation time and most of the contact details are returned when a single extension is requested.
+*/
 type Extension struct {
-	ID          int64            `json:"id,omitempty"`
-	Name        string           `json:"name,omitempty"`
-	Type        string           `json:"type,omitempty"`
-	Status      string           `json:"status,omitempty"`
-	ContactInfo ExtensionContact `json:"contact,omitempty"`
+	ID               int64                     `json:"id,omitempty"`
+	ExtensionNumber  string                    `json:"extensionNumber,omitempty"`
+	Name             string                    `json:"name,omitempty"`
+	Type             string                    `json:"type,omitempty"`
+	Status           string                    `json:"status,omitempty"`
+	ContactInfo      ExtensionContact          `json:"contact,omitempty"`
+	Site             SiteReference             `json:"site,omitempty"`
+	CostCenter       CostCenterReference       `json:"costCenter,omitempty"`
+	RegionalSettings ExtensionRegionalSettings `json:"regionalSettings,omitempty"`
+	// CreationTime is the time the extension was created, in ISO 8601 format.
+	CreationTime string `json:"creationTime,omitempty"`
 }
 
 type ExtensionContact struct {
-	FirstName string `json:"firstName,omitempty"`
-	LastName  string `json:"lastName,omitempty"`
-	Email     string `json:"email,omitempty"`
+	FirstName     string `json:"firstName,omitempty"`
+	LastName      string `json:"lastName,omitempty"`
+	Email         string `json:"email,omitempty"`
+	JobTitle      string `json:"jobTitle,omitempty"`
+	Department    string `json:"department,omitempty"`
+	BusinessPhone string `json:"businessPhone,omitempty"`
+	MobilePhone   string `json:"mobilePhone,omitempty"`
+	HireDate      string `json:"hireDate,omitempty"`
+}
+
+type CostCenterReference struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type ExtensionRegionalSettings struct {
+	Language ExtensionLanguage `json:"language,omitempty"`
 }
 
+// ExtensionLanguage is the language of the user interface of the extension, LocaleCode is like 'en-US'.
+type ExtensionLanguage struct {
+	ID         string `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	LocaleCode string `json:"localeCode,omitempty"`
+}
+
+// ExtensionCreateBody is the body of the request that creates an extension. The site and the roles are optional.
+type ExtensionCreateBody struct {
+	Contact          ExtensionContact `json:"contact"`
+	Type             string           `json:"type"`
+	ExtensionNumber  string           `json:"extensionNumber,omitempty"`
+	Site             *SiteReference   `json:"site,omitempty"`
+	Roles            []RoleReference  `json:"roles,omitempty"`
+	SendWelcomeEmail bool             `json:"sendWelcomeEmail,omitempty"`
+}
+
+// ExtensionStatusUpdateBody is the body of the request that updates the status of an extension.
+type ExtensionStatusUpdateBody struct {
+	Status string `json:"status"`
+}
+
+// Values the platform returns on the 'type' field of an Extension, also accepted by the 'type' filter of the extensions list.
+// Call queues are reported by the platform as 'Department' extensions.
+const (
+	ExtensionTypeUser             = "User"
+	ExtensionTypeDigitalUser      = "DigitalUser"
+	ExtensionTypeVirtualUser      = "VirtualUser"
+	ExtensionTypeDepartment       = "Department"
+	ExtensionTypeIvrMenu          = "IvrMenu"
+	ExtensionTypeSharedLinesGroup = "SharedLinesGroup"
+	ExtensionTypePagingOnly       = "PagingOnly"
+	ExtensionTypeParkLocation     = "ParkLocation"
+	ExtensionTypeLimited          = "Limited"
+)
+
+// UserExtensionTypes are the extension types that belong to a person.
+var UserExtensionTypes = []string{
+	ExtensionTypeUser,
+	ExtensionTypeDigitalUser,
+	ExtensionTypeVirtualUser,
+}
+
+// Values the platform returns on the 'status' field of an Extension.
+const (
+	ExtensionStatusEnabled      = "Enabled"
+	ExtensionStatusDisabled     = "Disabled"
+	ExtensionStatusFrozen       = "Frozen"
+	ExtensionStatusNotActivated = "NotActivated"
+	ExtensionStatusUnassigned   = "Unassigned"
+)
+
 // <-- Extension Response Structures
 
 // Role Response Structures -->
@@ -70,6 +174,28 @@ type Role struct {
 	Scope          string `json:"scope,omitempty"`
 	Hidden         bool   `json:"hidden,omitempty"`
 	SiteCompatible bool   `json:"siteCompatible,omitempty"`
+	// Permissions is only returned when a single role is requested.
+	Permissions []PermissionReference `json:"permissions,omitempty"`
+}
+
+// RoleReference points to a role by its ID.
+type RoleReference struct {
+	ID string `json:"id"`
+}
+
+// RoleBulkAssignBody is the body of the request that assigns a role to, and unassigns it from, several extensions.
+type RoleBulkAssignBody struct {
+	AddedExtensionIds   []string `json:"addedExtensionIds,omitempty"`
+	RemovedExtensionIds []string `json:"removedExtensionIds,omitempty"`
+}
+
+// RoleCreateBody is the body of the request that creates a custom role.
+type RoleCreateBody struct {
+	DisplayName    string                `json:"displayName"`
+	Description    string                `json:"description,omitempty"`
+	Scope          string                `json:"scope,omitempty"`
+	SiteCompatible bool                  `json:"siteCompatible,omitempty"`
+	Permissions    []PermissionReference `json:"permissions,omitempty"`
 }
 
 // <-- Role Response Structures
@@ -80,11 +206,185 @@ type UserRoleResponse struct {
 	Records []UserRole `json:"records,omitempty"`
 }
 
+// UserRole is a role assigned to an extension. When SiteRestricted is set, the assignment only applies to the listed Sites.
 type UserRole struct {
-	Id             string `json:"id,omitempty"`
-	AutoAssigned   bool   `json:"autoAssigned,omitempty"`
-	SiteRestricted bool   `json:"siteRestricted,omitempty"`
-	SiteCompatible bool   `json:"siteCompatible,omitempty"`
+	Id             string          `json:"id,omitempty"`
+	AutoAssigned   bool            `json:"autoAssigned,omitempty"`
+	SiteRestricted bool            `json:"siteRestricted,omitempty"`
+	SiteCompatible bool            `json:"siteCompatible,omitempty"`
+	Sites          []SiteReference `json:"sites,omitempty"`
 }
 
 // <-- Role Per User Response Structures
+
+// Permission Response Structures -->
+
+type PermissionResponse struct {
+	BasicResponse
+	Records []Permission `json:"records,omitempty"`
+}
+
+// Permission is an entry of the permissions dictionary of the platform, like 'EditAccounts' or 'ReadCallLog'.
+type Permission struct {
+	URI            string              `json:"uri,omitempty"`
+	ID             string              `json:"id,omitempty"`
+	DisplayName    string              `json:"displayName,omitempty"`
+	Description    string              `json:"description,omitempty"`
+	Assignable     bool                `json:"assignable,omitempty"`
+	ReadOnly       bool                `json:"readOnly,omitempty"`
+	SiteCompatible string              `json:"siteCompatible,omitempty"`
+	Category       PermissionReference `json:"category,omitempty"`
+}
+
+type PermissionCategoryResponse struct {
+	BasicResponse
+	Records []PermissionCategory `json:"records,omitempty"`
+}
+
+type PermissionCategory struct {
+	URI         string `json:"uri,omitempty"`
+	ID          string `json:"id,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// PermissionReference points to a permission, or to a permission category, by its ID.
+type PermissionReference struct {
+	URI string `json:"uri,omitempty"`
+	ID  string `json:"id,omitempty"`
+}
+
+// <-- Permission Response Structures
+
+// Call Queue Response Structures -->
+
+type CallQueueResponse struct {
+	BasicResponse
+	Records []CallQueue `json:"records,omitempty"`
+}
+
+type CallQueue struct {
+	URI             string `json:"uri,omitempty"`
+	ID              string `json:"id,omitempty"`
+	ExtensionNumber string `json:"extensionNumber,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Status          string `json:"status,omitempty"`
+}
+
+type CallQueueMemberResponse struct {
+	BasicResponse
+	Records []CallQueueMember `json:"records,omitempty"`
+}
+
+// CallQueueMember is the extension of a user that answers the calls of a queue.
+type CallQueueMember struct {
+	URI             string `json:"uri,omitempty"`
+	ID              string `json:"id,omitempty"`
+	ExtensionNumber string `json:"extensionNumber,omitempty"`
+	Name            string `json:"name,omitempty"`
+}
+
+type CallQueueManagerResponse struct {
+	Records []CallQueueManager `json:"records,omitempty"`
+}
+
+// CallQueueManager is the extension of a user allowed to manage a queue. Permission is either 'FullAccess' or 'Members'.
+type CallQueueManager struct {
+	Extension  CallQueueMember `json:"extension,omitempty"`
+	Permission string          `json:"permission,omitempty"`
+}
+
+// CallQueueBulkAssignBody is the body of the request that updates the members of a call queue.
+type CallQueueBulkAssignBody struct {
+	AddedExtensionIds   []string `json:"addedExtensionIds,omitempty"`
+	RemovedExtensionIds []string `json:"removedExtensionIds,omitempty"`
+}
+
+// <-- Call Queue Response Structures
+
+// Site Response Structures -->
+
+type SiteResponse struct {
+	BasicResponse
+	Records []Site `json:"records,omitempty"`
+}
+
+type Site struct {
+	URI             string `json:"uri,omitempty"`
+	ID              string `json:"id,omitempty"`
+	Name            string `json:"name,omitempty"`
+	ExtensionNumber string `json:"extensionNumber,omitempty"`
+	Code            string `json:"code,omitempty"`
+	Email           string `json:"email,omitempty"`
+}
+
+type SiteMemberResponse struct {
+	BasicResponse
+	Records []SiteMember `json:"records,omitempty"`
+}
+
+// SiteMember is an extension that belongs to a site. Type holds the extension type, like 'User' or 'IvrMenu'.
+type SiteMember struct {
+	URI             string `json:"uri,omitempty"`
+	ID              string `json:"id,omitempty"`
+	ExtensionNumber string `json:"extensionNumber,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Type            string `json:"type,omitempty"`
+}
+
+type SiteReference struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// <-- Site Response Structures
+
+// SCIM Response Structures -->
+
+type ScimListResponse struct {
+	Schemas      []string   `json:"schemas,omitempty"`
+	TotalResults int        `json:"totalResults,omitempty"`
+	ItemsPerPage int        `json:"itemsPerPage,omitempty"`
+	StartIndex   int        `json:"startIndex,omitempty"`
+	Resources    []ScimUser `json:"Resources,omitempty"`
+}
+
+// ScimUser is a user as exposed by the SCIM API. Its ID is the ID of the extension of the user on the REST API.
+type ScimUser struct {
+	ID           string             `json:"id,omitempty"`
+	ExternalID   string             `json:"externalId,omitempty"`
+	UserName     string             `json:"userName,omitempty"`
+	Name         ScimName           `json:"name,omitempty"`
+	Title        string             `json:"title,omitempty"`
+	Active       bool               `json:"active"`
+	Emails       []ScimMultiValue   `json:"emails,omitempty"`
+	PhoneNumbers []ScimMultiValue   `json:"phoneNumbers,omitempty"`
+	Enterprise   ScimEnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+}
+
+type ScimName struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	Formatted  string `json:"formatted,omitempty"`
+}
+
+// ScimMultiValue is an entry of a multi-valued attribute, like an email or a phone number. Type is like 'work' or 'mobile'.
+type ScimMultiValue struct {
+	Value   string `json:"value,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// ScimEnterpriseUser holds the attributes of the enterprise extension of the SCIM schema.
+type ScimEnterpriseUser struct {
+	Department     string      `json:"department,omitempty"`
+	EmployeeNumber string      `json:"employeeNumber,omitempty"`
+	Manager        ScimManager `json:"manager,omitempty"`
+}
+
+type ScimManager struct {
+	Value       string `json:"value,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// <-- SCIM Response Structures
@@ -41,3 +41,15 @@ func WithQueryParam(key string, value string) ReqOpt {
 		reqURL.RawQuery = q.Encode()
 	}
 }
+
+// WithQueryParamValues : Sets a query parameter that is repeated once per value, like 'type=User&type=DigitalUser'.
+func WithQueryParamValues(key string, values ...string) ReqOpt {
+	return func(reqURL *url.URL) {
+		q := reqURL.Query()
+		q.Del(key)
+		for _, value := range values {
+			q.Add(key, value)
+		}
+		reqURL.RawQuery = q.Encode()
+	}
+}
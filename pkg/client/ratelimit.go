@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+/*
+The platform throttles the requests by usage plan group (Light, Medium, Heavy and Auth), reporting the state of the group of each
+endpoint on the following headers. Once the limit of a group is reached, the platform answers with 429 and the Retry-After header.
+*/
+const (
+	rateLimitGroupHeader     = "X-Rate-Limit-Group"
+	rateLimitLimitHeader     = "X-Rate-Limit-Limit"
+	rateLimitRemainingHeader = "X-Rate-Limit-Remaining"
+	rateLimitWindowHeader    = "X-Rate-Limit-Window"
+	retryAfterHeader         = "Retry-After"
+
+	// maxRateLimitRetries is the number of times a throttled request is retried before the error is returned.
+	maxRateLimitRetries = 3
+	// defaultRetryAfter is used when a throttled response doesn't state how long to wait. It matches the window of every group.
+	defaultRetryAfter = 60 * time.Second
+)
+
+// parseRateLimit builds the rate limit description of the group of the request from the headers of its response.
+func parseRateLimit(statusCode int, header http.Header) *v2.RateLimitDescription {
+	if header == nil || header.Get(rateLimitLimitHeader) == "" {
+		return nil
+	}
+
+	limit, _ := strconv.ParseInt(header.Get(rateLimitLimitHeader), 10, 64)
+	remaining, _ := strconv.ParseInt(header.Get(rateLimitRemainingHeader), 10, 64)
+
+	// The window header states the length of the window, the reset time is estimated as the end of a window starting now.
+	resetAt := time.Now().Add(parseSeconds(header.Get(rateLimitWindowHeader), defaultRetryAfter))
+
+	rateLimitStatus := v2.RateLimitDescription_STATUS_OK
+	if statusCode == http.StatusTooManyRequests {
+		rateLimitStatus = v2.RateLimitDescription_STATUS_OVERLIMIT
+		remaining = 0
+		resetAt = time.Now().Add(retryAfter(header))
+	}
+
+	return &v2.RateLimitDescription{
+		Status:    rateLimitStatus,
+		Limit:     limit,
+		Remaining: remaining,
+		ResetAt:   timestamppb.New(resetAt),
+	}
+}
+
+// retryAfter returns how long to wait before retrying a throttled request.
+func retryAfter(header http.Header) time.Duration {
+	if header.Get(retryAfterHeader) != "" {
+		return parseSeconds(header.Get(retryAfterHeader), defaultRetryAfter)
+	}
+
+	return parseSeconds(header.Get(rateLimitWindowHeader), defaultRetryAfter)
+}
+
+func parseSeconds(value string, fallback time.Duration) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return fallback
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// waitForRateLimit blocks until the throttled request can be retried, or the context is done.
+func waitForRateLimit(ctx context.Context, header http.Header, attempt int) error {
+	wait := retryAfter(header)
+
+	ctxzap.Extract(ctx).Warn("ringcentral-connector: request rate exceeded, waiting before retrying",
+		zap.String("rate_limit_group", header.Get(rateLimitGroupHeader)),
+		zap.Duration("retry_after", wait),
+		zap.Int("attempt", attempt))
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
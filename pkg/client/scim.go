@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// The SCIM API is served from the root of the platform server, next to the REST API instead of under it.
+const scimUsers = "/scim/v2/Users"
+
+/*
+ListScimUsers returns an array of the users of the company as exposed by the SCIM API, which carries the attributes of the HR systems
+(external ID, department, employee number, manager) and the 'active' flag. The page options are mapped into the 1-based startIndex
+and the count of SCIM, so the page tokens work the same way as on the REST API.
+*/
+func (c *RingCentralClient) ListScimUsers(ctx context.Context, pageOps PageOptions) ([]ScimUser, string, *v2.RateLimitDescription, error) {
+	var response ScimListResponse
+
+	perPage := pageOps.PerPage
+	if perPage <= 0 || perPage > ItemsPerPage {
+		perPage = ItemsPerPage
+	}
+
+	page := pageOps.Page
+	if page == 0 {
+		page = 1
+	}
+
+	queryUrl, err := url.JoinPath(c.scimBaseURL(), scimUsers)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	startIndex := (page-1)*perPage + 1
+	header, err := c.doRequest(
+		ctx,
+		http.MethodGet,
+		queryUrl,
+		&response,
+		nil,
+		WithQueryParam("startIndex", strconv.Itoa(startIndex)),
+		WithQueryParam("count", strconv.Itoa(perPage)),
+	)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var nextPage string
+	if len(response.Resources) > 0 && startIndex+len(response.Resources) <= response.TotalResults {
+		nextPage = strconv.Itoa(page + 1)
+	}
+
+	return response.Resources, nextPage, parseRateLimit(http.StatusOK, header), nil
+}
+
+// FindScimUser returns the SCIM user whose userName, the email they log in with, matches the given one, or nil if there's none.
+func (c *RingCentralClient) FindScimUser(ctx context.Context, userName string) (*ScimUser, error) {
+	var response ScimListResponse
+
+	queryUrl, err := url.JoinPath(c.scimBaseURL(), scimUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.doUncachedRequest(ctx, http.MethodGet, queryUrl, &response, nil, WithQueryParam("filter", scimEqualsFilter("userName", userName)))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Resources) == 0 {
+		return nil, nil
+	}
+
+	return &response.Resources[0], nil
+}
+
+func (c *RingCentralClient) scimBaseURL() string {
+	return strings.TrimSuffix(c.baseURL, restAPIPath)
+}
+
+// scimEqualsFilter returns the SCIM filter that matches the attribute with the value, escaping the quotes of the value.
+func scimEqualsFilter(attribute string, value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+
+	return fmt.Sprintf(`%s eq "%s"`, attribute, value)
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestListScimUsers tests that the page options are mapped into the startIndex and the count of SCIM, and back into page tokens.
+func TestListScimUsers(t *testing.T) {
+	var startIndexes []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/scim/v2/Users" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		startIndexes = append(startIndexes, r.URL.Query().Get("startIndex"))
+		assert.Equal(t, "2", r.URL.Query().Get("count"))
+
+		startIndex, err := strconv.Atoi(r.URL.Query().Get("startIndex"))
+		require.NoError(t, err)
+
+		// Three users, served two per page.
+		resources := `{"id":"1","active":true},{"id":"2","active":false}`
+		if startIndex > 1 {
+			resources = `{"id":"3","active":true}`
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"totalResults":3,"itemsPerPage":2,"startIndex":` + strconv.Itoa(startIndex) + `,"Resources":[` + resources + `]}`))
+	}))
+	defer server.Close()
+
+	c, err := New(context.Background(), WithBaseURL(server.URL), WithAccessToken("token"))
+	require.NoError(t, err)
+
+	users, nextPage, _, err := c.ListScimUsers(context.Background(), PageOptions{PerPage: 2})
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, "2", nextPage)
+	assert.False(t, users[1].Active)
+
+	users, nextPage, _, err = c.ListScimUsers(context.Background(), PageOptions{Page: 2, PerPage: 2})
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Empty(t, nextPage)
+
+	assert.Equal(t, []string{"1", "3"}, startIndexes)
+}
+
+// TestFindScimUser tests the lookup of a SCIM user by userName, and the escaping of the value of the filter.
+func TestFindScimUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Query().Get("filter") == `userName eq "jane.doe@example.com"` {
+			_, _ = w.Write([]byte(`{"totalResults":1,"Resources":[{"id":"42","userName":"jane.doe@example.com","active":true}]}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"totalResults":0}`))
+	}))
+	defer server.Close()
+
+	c, err := New(context.Background(), WithBaseURL(server.URL), WithAccessToken("token"))
+	require.NoError(t, err)
+
+	user, err := c.FindScimUser(context.Background(), "jane.doe@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	assert.Equal(t, "42", user.ID)
+
+	user, err = c.FindScimUser(context.Background(), "john.doe@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, user)
+
+	assert.Equal(t, `userName eq "say \"hi\" \\ bye"`, scimEqualsFilter("userName", `say "hi" \ bye`))
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+/*
+cachedToken is the session of the client persisted on the token cache. The server, app and grant it was issued for are
+stored along with it, so a session is never reused by a client configured for a different one.
+*/
+type cachedToken struct {
+	BaseURL               string    `json:"base_url"`
+	ClientID              string    `json:"client_id"`
+	GrantType             string    `json:"grant_type"`
+	AccessToken           string    `json:"access_token"`
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshToken          string    `json:"refresh_token,omitempty"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at,omitempty"`
+	Scopes                []string  `json:"scopes,omitempty"`
+}
+
+// grantType returns the grant type of the token source of the client. The caller must hold tokenMtx.
+func (c *RingCentralClient) grantType() string {
+	if c.Config.TokenSource == nil {
+		return ""
+	}
+
+	return c.Config.TokenSource.GrantForm().Get("grant_type")
+}
+
+/*
+loadCachedToken restores the session persisted on the token cache, if any. Errors reading the cache aren't returned,
+since the client can always start a new session. The caller must hold tokenMtx.
+*/
+func (c *RingCentralClient) loadCachedToken(ctx context.Context) {
+	if c.tokenCachePath == "" {
+		return
+	}
+
+	l := ctxzap.Extract(ctx)
+
+	content, err := os.ReadFile(c.tokenCachePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			l.Warn("ringcentral-connector: error reading the token cache", zap.String("path", c.tokenCachePath), zap.Error(err))
+		}
+		return
+	}
+
+	var cached cachedToken
+	err = json.Unmarshal(content, &cached)
+	if err != nil {
+		l.Warn("ringcentral-connector: error parsing the token cache", zap.String("path", c.tokenCachePath), zap.Error(err))
+		return
+	}
+
+	if cached.BaseURL != c.baseURL || cached.ClientID != c.Config.ClientID || cached.GrantType != c.grantType() {
+		l.Debug("ringcentral-connector: ignoring the token cache, it was issued for a different configuration")
+		return
+	}
+
+	c.accessToken = cached.AccessToken
+	c.accessTokenExpiresAt = cached.AccessTokenExpiresAt
+	c.refreshToken = cached.RefreshToken
+	c.refreshTokenExpiresAt = cached.RefreshTokenExpiresAt
+	c.scopes = cached.Scopes
+
+	// The refresh token configured for the refresh token grant was already rotated, the cached one replaces it.
+	if c.refreshToken != "" && c.Config.TokenSource != nil {
+		c.Config.TokenSource.OnToken(&TokenResponse{RefreshToken: c.refreshToken})
+	}
+}
+
+/*
+saveCachedToken persists the current session on the token cache, when it's enabled. The file is replaced atomically,
+so a run that's interrupted never leaves a partial session behind. The caller must hold tokenMtx.
+*/
+func (c *RingCentralClient) saveCachedToken(ctx context.Context) {
+	if c.tokenCachePath == "" {
+		return
+	}
+
+	err := c.writeCachedToken()
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("ringcentral-connector: error writing the token cache", zap.String("path", c.tokenCachePath), zap.Error(err))
+	}
+}
+
+func (c *RingCentralClient) writeCachedToken() error {
+	content, err := json.Marshal(cachedToken{
+		BaseURL:               c.baseURL,
+		ClientID:              c.Config.ClientID,
+		GrantType:             c.grantType(),
+		AccessToken:           c.accessToken,
+		AccessTokenExpiresAt:  c.accessTokenExpiresAt,
+		RefreshToken:          c.refreshToken,
+		RefreshTokenExpiresAt: c.refreshTokenExpiresAt,
+		Scopes:                c.scopes,
+	})
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(c.tokenCachePath), filepath.Base(c.tokenCachePath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(content)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), c.tokenCachePath)
+}
@@ -0,0 +1,288 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-ringcentral/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	callQueueMemberEntitlement  = "member"
+	callQueueManagerEntitlement = "manager"
+)
+
+type callQueueBuilder struct {
+	resourceType    *v2.ResourceType
+	client          *client.RingCentralClient
+	excludeInactive bool
+}
+
+func (b *callQueueBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return callQueueResourceType
+}
+
+// List returns all the call queues of the company as resource objects.
+func (b *callQueueBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var callQueueResources []*v2.Resource
+
+	bag, pageToken, err := getToken(pToken, callQueueResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	callQueues, nextPageToken, rateLimit, err := b.client.ListCallQueues(ctx, client.PageOptions{
+		Page:    pageToken,
+		PerPage: pToken.Size,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, callQueue := range callQueues {
+		callQueueResource, err := parseIntoCallQueueResource(callQueue)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		callQueueResources = append(callQueueResources, callQueueResource)
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return callQueueResources, nextPageToken, rateLimitAnnotations(rateLimit), nil
+}
+
+// Entitlements returns the membership and the management entitlements of a call queue.
+func (b *callQueueBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	memberOptions := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(userResourceType),
+		entitlement.WithDisplayName(fmt.Sprintf("%s Call Queue Member", resource.DisplayName)),
+		entitlement.WithDescription(fmt.Sprintf("Answers the calls of the %s call queue", resource.DisplayName)),
+	}
+
+	managerOptions := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(userResourceType),
+		entitlement.WithDisplayName(fmt.Sprintf("%s Call Queue Manager", resource.DisplayName)),
+		entitlement.WithDescription(fmt.Sprintf("Manages the %s call queue", resource.DisplayName)),
+	}
+
+	return []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(resource, callQueueMemberEntitlement, memberOptions...),
+		entitlement.NewPermissionEntitlement(resource, callQueueManagerEntitlement, managerOptions...),
+	}, "", nil, nil
+}
+
+/*
+Grants returns the members of the call queue, page by page, followed by its managers. The inactive users are skipped when they're excluded from the sync.
+The pagination bag keeps one state for each list, the entitlement name is stored as the ResourceTypeID of the state.
+*/
+func (b *callQueueBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	var (
+		grants    []*v2.Grant
+		rateLimit *v2.RateLimitDescription
+	)
+
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(pToken.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if bag.Current() == nil {
+		bag.Push(pagination.PageState{ResourceTypeID: callQueueManagerEntitlement})
+		bag.Push(pagination.PageState{ResourceTypeID: callQueueMemberEntitlement})
+	}
+
+	excludedUserIDs, err := listExcludedUserIDs(ctx, b.client, b.excludeInactive)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	switch bag.ResourceTypeID() {
+	case callQueueMemberEntitlement:
+		var pageToken int
+		if bag.PageToken() != "" {
+			pageToken, err = strconv.Atoi(bag.PageToken())
+			if err != nil {
+				return nil, "", nil, err
+			}
+		}
+
+		members, nextPageToken, membersRateLimit, err := b.client.ListCallQueueMembers(ctx, resource.Id.Resource, client.PageOptions{
+			Page:    pageToken,
+			PerPage: pToken.Size,
+		})
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rateLimit = membersRateLimit
+		for _, member := range members {
+			if excludedUserIDs[member.ID] {
+				continue
+			}
+			grants = append(grants, grant.NewGrant(resource, callQueueMemberEntitlement, newUserResourceID(member.ID)))
+		}
+
+		err = bag.Next(nextPageToken)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+	case callQueueManagerEntitlement:
+		managers, managersRateLimit, err := b.client.ListCallQueueManagers(ctx, resource.Id.Resource)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rateLimit = managersRateLimit
+
+		for _, manager := range managers {
+			if excludedUserIDs[manager.Extension.ID] {
+				continue
+			}
+			grants = append(grants, grant.NewGrant(
+				resource,
+				callQueueManagerEntitlement,
+				newUserResourceID(manager.Extension.ID),
+				grant.WithGrantMetadata(map[string]interface{}{
+					"permission": manager.Permission,
+				}),
+			))
+		}
+
+		err = bag.Next("")
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+	default:
+		return nil, "", nil, fmt.Errorf("ringcentral-connector: unexpected call queue grants page: %s", bag.ResourceTypeID())
+	}
+
+	nextPageToken, err := bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return grants, nextPageToken, rateLimitAnnotations(rateLimit), nil
+}
+
+// Grant adds a user to the members of a call queue. Managers of the queues can't be provisioned.
+func (b *callQueueBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	if principal.Id.ResourceType != userResourceType.Id {
+		l.Warn("ringcentral-connector: only users can be granted with call queue membership",
+			zap.String("principal_id", principal.Id.Resource),
+			zap.String("principal_type", principal.Id.ResourceType))
+		return nil, fmt.Errorf("ringcentral-connector: only users can be granted with call queue membership")
+	}
+
+	if entitlement.Slug != callQueueMemberEntitlement {
+		return nil, fmt.Errorf("ringcentral-connector: only the call queue membership can be granted, got '%s'", entitlement.Slug)
+	}
+
+	callQueueID := entitlement.Resource.Id.Resource
+	extensionID := principal.Id.Resource
+
+	isMember, err := b.client.IsCallQueueMember(ctx, callQueueID, extensionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if isMember {
+		l.Debug("ringcentral-connector: user is already a member of the call queue",
+			zap.String("call_queue_id", callQueueID),
+			zap.String("principal_id", extensionID))
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	err = b.client.UpdateCallQueueMembers(ctx, callQueueID, []string{extensionID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// Revoke removes a user from the members of a call queue.
+func (b *callQueueBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	if grant.Entitlement.Slug != callQueueMemberEntitlement {
+		return nil, fmt.Errorf("ringcentral-connector: only the call queue membership can be revoked, got '%s'", grant.Entitlement.Slug)
+	}
+
+	callQueueID := grant.Entitlement.Resource.Id.Resource
+	extensionID := grant.Principal.Id.Resource
+
+	isMember, err := b.client.IsCallQueueMember(ctx, callQueueID, extensionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isMember {
+		l.Debug("ringcentral-connector: user is not a member of the call queue",
+			zap.String("call_queue_id", callQueueID),
+			zap.String("principal_id", extensionID))
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	err = b.client.UpdateCallQueueMembers(ctx, callQueueID, nil, []string{extensionID})
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// parseIntoCallQueueResource - This function parses a Call Queue into a Group Resource.
+func parseIntoCallQueueResource(callQueue client.CallQueue) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"call_queue_id":    callQueue.ID,
+		"name":             callQueue.Name,
+		"extension_number": callQueue.ExtensionNumber,
+		"status":           callQueue.Status,
+	}
+
+	groupTraits := []rs.GroupTraitOption{
+		rs.WithGroupProfile(profile),
+	}
+
+	ret, err := rs.NewGroupResource(
+		callQueue.Name,
+		callQueueResourceType,
+		callQueue.ID,
+		groupTraits,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func newCallQueueBuilder(c *client.RingCentralClient, excludeInactive bool) *callQueueBuilder {
+	return &callQueueBuilder{
+		resourceType:    callQueueResourceType,
+		client:          c,
+		excludeInactive: excludeInactive,
+	}
+}
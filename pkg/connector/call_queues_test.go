@@ -0,0 +1,163 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-ringcentral/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCallQueue serves the members of the call queue 10, applying the bulk-assign requests it receives.
+type fakeCallQueue struct {
+	mtx         sync.Mutex
+	members     []string
+	bulkAssigns []client.CallQueueBulkAssignBody
+}
+
+func (f *fakeCallQueue) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/restapi/v1.0/account/~/call-queues/10/members":
+		response := client.CallQueueMemberResponse{BasicResponse: client.BasicResponse{Paging: client.Paging{Page: 1, TotalPages: 1}}}
+		for _, member := range f.members {
+			response.Records = append(response.Records, client.CallQueueMember{ID: member})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/restapi/v1.0/account/~/call-queues/10/bulk-assign":
+		var body client.CallQueueBulkAssignBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.bulkAssigns = append(f.bulkAssigns, body)
+
+		f.members = append(f.members, body.AddedExtensionIds...)
+		f.members = slices.DeleteFunc(f.members, func(member string) bool {
+			return slices.Contains(body.RemovedExtensionIds, member)
+		})
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeCallQueue) setMembers(members ...string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.members = members
+}
+
+/*
+TestCallQueueBuilder_GrantRevoke tests that the membership of the call queue is only updated when needed. The members are synced before
+each case, and then updated behind the connector, so the membership check must not read the pages of members cached by the sync.
+*/
+func TestCallQueueBuilder_GrantRevoke(t *testing.T) {
+	tests := []struct {
+		name          string
+		syncedMembers []string
+		members       []string
+		revoke        bool
+		annotation    interface{}
+		bulkAssign    *client.CallQueueBulkAssignBody
+	}{
+		{
+			name:          "grant to a member",
+			syncedMembers: []string{"1"},
+			members:       []string{"1"},
+			annotation:    &v2.GrantAlreadyExists{},
+		},
+		{
+			name:       "grant to a member added after the sync",
+			members:    []string{"1"},
+			annotation: &v2.GrantAlreadyExists{},
+		},
+		{
+			name:          "grant to a member removed after the sync",
+			syncedMembers: []string{"1"},
+			bulkAssign:    &client.CallQueueBulkAssignBody{AddedExtensionIds: []string{"1"}},
+		},
+		{
+			name:       "revoke from a non member",
+			revoke:     true,
+			annotation: &v2.GrantAlreadyRevoked{},
+		},
+		{
+			name:          "revoke from a member removed after the sync",
+			syncedMembers: []string{"1"},
+			revoke:        true,
+			annotation:    &v2.GrantAlreadyRevoked{},
+		},
+		{
+			name:       "revoke from a member added after the sync",
+			members:    []string{"1", "2"},
+			revoke:     true,
+			bulkAssign: &client.CallQueueBulkAssignBody{RemovedExtensionIds: []string{"1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeCallQueue{}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+			require.NoError(t, err)
+
+			b := newCallQueueBuilder(c, false)
+			callQueue := &v2.Resource{Id: &v2.ResourceId{ResourceType: callQueueResourceType.Id, Resource: "10"}}
+			user := &v2.Resource{Id: newUserResourceID("1")}
+			memberEntitlement := &v2.Entitlement{
+				Id:       entitlement.NewEntitlementID(callQueue, callQueueMemberEntitlement),
+				Resource: callQueue,
+				Slug:     callQueueMemberEntitlement,
+			}
+
+			fake.setMembers(tt.syncedMembers...)
+			_, _, _, err = b.Grants(context.Background(), callQueue, &pagination.Token{Size: client.ItemsPerPage})
+			require.NoError(t, err)
+			fake.setMembers(tt.members...)
+
+			var annos annotations.Annotations
+			if tt.revoke {
+				annos, err = b.Revoke(context.Background(), &v2.Grant{Entitlement: memberEntitlement, Principal: user})
+			} else {
+				annos, err = b.Grant(context.Background(), user, memberEntitlement)
+			}
+			require.NoError(t, err)
+
+			switch expected := tt.annotation.(type) {
+			case *v2.GrantAlreadyExists:
+				assert.True(t, annos.Contains(expected))
+			case *v2.GrantAlreadyRevoked:
+				assert.True(t, annos.Contains(expected))
+			default:
+				assert.Empty(t, annos)
+			}
+
+			if tt.bulkAssign == nil {
+				assert.Empty(t, fake.bulkAssigns)
+				return
+			}
+			assert.Equal(t, []client.CallQueueBulkAssignBody{*tt.bulkAssign}, fake.bulkAssigns)
+		})
+	}
+}
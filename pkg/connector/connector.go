@@ -2,24 +2,118 @@ package connector
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"slices"
+	"strings"
 
 	"github.com/conductorone/baton-ringcentral/pkg/client"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Authentication modes supported by the connector, each one maps into a token source of the client.
+const (
+	AuthModeJWT               = "jwt"
+	AuthModeClientCredentials = "client-credentials"
+	AuthModeRefreshToken      = "refresh-token"
+)
+
+/*
+Deprovisioning policies, they tell what deleting a user does on the platform. Disabled extensions keep their data and phone numbers,
+and can be enabled again, while deleted extensions are gone for good.
+*/
+const (
+	DeprovisioningPolicyDisable = "disable"
+	DeprovisioningPolicyDelete  = "delete"
+)
+
+/*
+User sync modes, they tell which API the users are read from. The extensions of the REST API are read by default,
+while the SCIM API adds the attributes of the HR systems, like the department, the employee number and the manager.
+*/
+const (
+	UserSyncModeExtension = "extension"
+	UserSyncModeSCIM      = "scim"
+)
+
+// Config holds the settings of the connector, as read from the configuration fields.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	AuthMode     string
+	JWT          string
+	RefreshToken string
+	ServerURL    string
+	// TokenCachePath is the file the session is persisted to so it's reused across runs, instead of being revoked on Close.
+	TokenCachePath string
+
+	ExcludeInactive bool
+	// UserSyncMode is either UserSyncModeExtension, the default, or UserSyncModeSCIM. ExcludeInactive only applies to the former.
+	UserSyncMode string
+	// ProtectedRoles are the IDs or display names of the roles that are never revoked from the extension the connector authenticates as,
+	// nor from their last enabled holder, unless AllowUnsafeRevokes is set. DefaultProtectedRoles is used when it's empty.
+	ProtectedRoles     []string
+	AllowUnsafeRevokes bool
+	// FallbackRoleID is the role assigned to the users whose only role is revoked. The default role of the account is used when it's empty.
+	FallbackRoleID string
+	// DeprovisioningPolicy is either DeprovisioningPolicyDisable, the default, or DeprovisioningPolicyDelete.
+	DeprovisioningPolicy string
+	// ReclaimPhoneNumbers moves the phone numbers and devices of the deleted users to the inventory of the account, instead of removing them.
+	ReclaimPhoneNumbers bool
+	// Provisioning reports whether the provisioning actions are enabled, so Validate also checks the permissions they require.
+	Provisioning bool
+}
+
+/*
+App permissions (scopes) required by the connector. Syncing reads the account data (extensions, roles, call queues and sites),
+while provisioning edits the call queue members and the roles assigned to the users (EditExtensions and RoleManagement),
+and creates, disables and deletes the extensions of the users (EditAccounts).
+*/
+const (
+	scopeReadAccounts   = "ReadAccounts"
+	scopeEditAccounts   = "EditAccounts"
+	scopeEditExtensions = "EditExtensions"
+	scopeRoleManagement = "RoleManagement"
+)
+
+var (
+	syncScopes         = []string{scopeReadAccounts}
+	provisioningScopes = []string{scopeEditAccounts, scopeEditExtensions, scopeRoleManagement}
 )
 
 type Connector struct {
-	client *client.RingCentralClient
+	client               *client.RingCentralClient
+	excludeInactive      bool
+	userSyncMode         string
+	provisioning         bool
+	revokeGuard          *revokeGuard
+	deprovisioningPolicy string
+	reclaimPhoneNumbers  bool
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (d *Connector) ResourceSyncers(_ context.Context) []connectorbuilder.ResourceSyncer {
+	// The grants skip the same users as the user list, which doesn't exclude the inactive ones on the SCIM sync mode.
+	excludeInactiveGrants := d.excludeInactive && d.userSyncMode == UserSyncModeExtension
+
 	return []connectorbuilder.ResourceSyncer{
-		newUserBuilder(d.client),
-		newRoleBuilder(d.client),
+		newUserBuilder(d.client, d.excludeInactive, d.userSyncMode, d.revokeGuard, d.deprovisioningPolicy, d.reclaimPhoneNumbers),
+		newRoleBuilder(d.client, d.revokeGuard),
+		newPermissionBuilder(d.client),
+		newCallQueueBuilder(d.client, excludeInactiveGrants),
+		newSiteBuilder(d.client, excludeInactiveGrants),
+		newExtensionBuilder(d.client, ivrMenuResourceType, client.ExtensionTypeIvrMenu),
+		newExtensionBuilder(d.client, sharedLinesGroupResourceType, client.ExtensionTypeSharedLinesGroup),
+		newExtensionBuilder(d.client, pagingOnlyResourceType, client.ExtensionTypePagingOnly),
+		newExtensionBuilder(d.client, parkLocationResourceType, client.ExtensionTypeParkLocation),
+		newExtensionBuilder(d.client, limitedExtensionResourceType, client.ExtensionTypeLimited),
 	}
 }
 
@@ -32,30 +126,186 @@ func (d *Connector) Asset(_ context.Context, _ *v2.AssetRef) (string, io.ReadClo
 // Metadata returns metadata about the connector.
 func (d *Connector) Metadata(_ context.Context) (*v2.ConnectorMetadata, error) {
 	return &v2.ConnectorMetadata{
-		DisplayName: "Baton RingCentral Connector",
-		Description: "Connector to sync users and permissions data from RingCentral. It allows the grant and revoke of user roles.",
+		DisplayName:           "Baton RingCentral Connector",
+		Description:           "Connector to sync users and permissions data from RingCentral. It allows the grant and revoke of user roles.",
+		AccountCreationSchema: accountCreationSchema(),
 	}, nil
 }
 
+// accountCreationSchema describes the fields used to create the extension of a new user.
+func accountCreationSchema() *v2.ConnectorAccountCreationSchema {
+	fields := []struct {
+		name        string
+		displayName string
+		description string
+		placeholder string
+		required    bool
+	}{
+		{accountEmailField, "Email", "Email of the user, the welcome email is sent to it", "user@example.com", true},
+		{accountFirstNameField, "First name", "First name of the user", "John", true},
+		{accountLastNameField, "Last name", "Last name of the user", "Smith", true},
+		{accountExtensionNumberField, "Extension number", "Extension number of the user, the platform picks one if it's not set", "101", false},
+		{accountSiteIDField, "Site ID", "ID of the site the user belongs to, the main site is used if it's not set", "", false},
+		{accountRoleIDField, "Role ID", "ID of the role assigned to the user, the default role of the account is used if it's not set", "", false},
+	}
+
+	fieldMap := make(map[string]*v2.ConnectorAccountCreationSchema_Field, len(fields))
+	var order int32
+	for _, f := range fields {
+		order++
+		fieldMap[f.name] = &v2.ConnectorAccountCreationSchema_Field{
+			DisplayName: f.displayName,
+			Required:    f.required,
+			Description: f.description,
+			Placeholder: f.placeholder,
+			Order:       order,
+			Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+				StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+			},
+		}
+	}
+
+	return &v2.ConnectorAccountCreationSchema{
+		FieldMap: fieldMap,
+	}
+}
+
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
 // to be sure that they are valid.
-func (d *Connector) Validate(_ context.Context) (annotations.Annotations, error) {
+func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	account, err := d.client.GetAccount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ringcentral-connector: failed to get the account of the credentials: %w", err)
+	}
+
+	extension, err := d.client.GetCurrentExtension(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ringcentral-connector: failed to get the extension of the credentials: %w", err)
+	}
+
+	l.Debug("ringcentral-connector: credentials validated",
+		zap.Int64("account_id", account.ID),
+		zap.String("account_status", account.Status),
+		zap.Int64("extension_id", extension.ID),
+		zap.String("extension_type", extension.Type))
+
+	err = validateScopes(d.client.GrantedScopes(), d.provisioning)
+	if err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
+// Close ends the session of the connector on the platform, it's called at the end of each sync.
+func (d *Connector) Close(ctx context.Context) error {
+	return d.client.Close(ctx)
+}
+
+/*
+validateScopes checks that the app permissions granted to the credentials cover the ones required by the enabled features,
+returning an error that lists the missing ones for sync and for provisioning. Unknown scopes (nil) aren't checked.
+*/
+func validateScopes(granted []string, provisioning bool) error {
+	if granted == nil {
+		return nil
+	}
+
+	var missing []string
+
+	missingSync := missingScopes(granted, syncScopes)
+	if len(missingSync) > 0 {
+		missing = append(missing, fmt.Sprintf("sync requires %s", strings.Join(missingSync, ", ")))
+	}
+
+	if provisioning {
+		missingProvisioning := missingScopes(granted, provisioningScopes)
+		if len(missingProvisioning) > 0 {
+			missing = append(missing, fmt.Sprintf("provisioning requires %s", strings.Join(missingProvisioning, ", ")))
+		}
+	}
+
+	if len(missing) > 0 {
+		return status.Errorf(
+			codes.PermissionDenied,
+			"ringcentral-connector: the app is missing permissions (%s), granted permissions: %s",
+			strings.Join(missing, "; "),
+			strings.Join(granted, ", "),
+		)
+	}
+
+	return nil
+}
+
+func missingScopes(granted []string, required []string) []string {
+	var missing []string
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+
+	return missing
+}
+
 // New returns a new instance of the connector.
-func New(ctx context.Context, rcClientID, rcClientSecret, rcJWT string) (*Connector, error) {
-	c, err := client.New(
-		ctx,
-		client.WithClientID(rcClientID),
-		client.WithClientSecret(rcClientSecret),
-		client.WithJWT(rcJWT),
-	)
+func New(ctx context.Context, cfg Config) (*Connector, error) {
+	opts := []client.Option{
+		client.WithBaseURL(cfg.ServerURL),
+		client.WithClientID(cfg.ClientID),
+		client.WithClientSecret(cfg.ClientSecret),
+		client.WithTokenCache(cfg.TokenCachePath),
+		client.WithFallbackRole(cfg.FallbackRoleID),
+	}
+
+	switch cfg.AuthMode {
+	case AuthModeJWT, "":
+		opts = append(opts, client.WithJWT(cfg.JWT))
+	case AuthModeClientCredentials:
+		opts = append(opts, client.WithClientCredentials())
+	case AuthModeRefreshToken:
+		opts = append(opts, client.WithRefreshToken(cfg.RefreshToken))
+	default:
+		return nil, fmt.Errorf("ringcentral-connector: unsupported authentication mode '%s'", cfg.AuthMode)
+	}
+
+	c, err := client.New(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
 
+	protectedRoles := cfg.ProtectedRoles
+	if len(protectedRoles) == 0 {
+		protectedRoles = DefaultProtectedRoles
+	}
+
+	userSyncMode := cfg.UserSyncMode
+	switch userSyncMode {
+	case "":
+		userSyncMode = UserSyncModeExtension
+	case UserSyncModeExtension, UserSyncModeSCIM:
+	default:
+		return nil, fmt.Errorf("ringcentral-connector: unsupported user sync mode '%s'", cfg.UserSyncMode)
+	}
+
+	deprovisioningPolicy := cfg.DeprovisioningPolicy
+	switch deprovisioningPolicy {
+	case "":
+		deprovisioningPolicy = DeprovisioningPolicyDisable
+	case DeprovisioningPolicyDisable, DeprovisioningPolicyDelete:
+	default:
+		return nil, fmt.Errorf("ringcentral-connector: unsupported deprovisioning policy '%s'", cfg.DeprovisioningPolicy)
+	}
+
 	return &Connector{
-		client: c,
+		client:               c,
+		excludeInactive:      cfg.ExcludeInactive,
+		userSyncMode:         userSyncMode,
+		provisioning:         cfg.Provisioning,
+		revokeGuard:          newRevokeGuard(c, protectedRoles, cfg.AllowUnsafeRevokes),
+		deprovisioningPolicy: deprovisioningPolicy,
+		reclaimPhoneNumbers:  cfg.ReclaimPhoneNumbers,
 	}, nil
 }
@@ -0,0 +1,51 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestValidateScopes tests the check of the app permissions against the features enabled on the connector.
+func TestValidateScopes(t *testing.T) {
+	tests := []struct {
+		name         string
+		granted      []string
+		provisioning bool
+		missing      []string
+	}{
+		{name: "unknown scopes", granted: nil, provisioning: true},
+		{name: "sync only", granted: []string{"ReadAccounts"}},
+		{name: "sync and provisioning", granted: []string{"ReadAccounts", "EditAccounts", "EditExtensions", "RoleManagement"}, provisioning: true},
+		{
+			name:         "missing account creation",
+			granted:      []string{"ReadAccounts", "EditExtensions", "RoleManagement"},
+			provisioning: true,
+			missing:      []string{"provisioning requires EditAccounts"},
+		},
+		{name: "missing sync", granted: []string{"ReadCallLog"}, missing: []string{"sync requires ReadAccounts"}},
+		{
+			name:         "missing sync and provisioning",
+			granted:      []string{"EditExtensions"},
+			provisioning: true,
+			missing:      []string{"sync requires ReadAccounts", "provisioning requires EditAccounts, RoleManagement"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateScopes(tt.granted, tt.provisioning)
+			if len(tt.missing) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.Equal(t, codes.PermissionDenied, status.Code(err))
+			for _, missing := range tt.missing {
+				assert.ErrorContains(t, err, missing)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/conductorone/baton-ringcentral/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+/*
+extensionBuilder syncs the extensions of the platform that aren't users (call queues, IVR menus, park locations, etc.).
+Each builder is bound to a resource type and to the extension types that are listed as that resource type.
+*/
+type extensionBuilder struct {
+	resourceType   *v2.ResourceType
+	client         *client.RingCentralClient
+	extensionTypes []string
+}
+
+func (b *extensionBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return b.resourceType
+}
+
+// List returns the extensions of the types bound to the builder as resource objects.
+func (b *extensionBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var extensionResources []*v2.Resource
+
+	bag, pageToken, err := getToken(pToken, b.resourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	extensions, nextPageToken, rateLimit, err := b.client.ListExtensions(ctx, b.extensionTypes, client.PageOptions{
+		Page:    pageToken,
+		PerPage: pToken.Size,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, extension := range extensions {
+		extensionResource, err := parseIntoExtensionResource(extension, b.resourceType)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		extensionResources = append(extensionResources, extensionResource)
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return extensionResources, nextPageToken, rateLimitAnnotations(rateLimit), nil
+}
+
+// Entitlements always returns an empty slice for extensions.
+func (b *extensionBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for extensions since they don't have any entitlements.
+func (b *extensionBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// parseIntoExtensionResource - This function parses an Extension that doesn't belong to a person into a Resource of the given type.
+func parseIntoExtensionResource(extension client.Extension, resourceType *v2.ResourceType) (*v2.Resource, error) {
+	displayName := extension.Name
+	if displayName == "" {
+		displayName = extension.ContactInfo.Email
+	}
+
+	ret, err := rs.NewResource(
+		displayName,
+		resourceType,
+		extension.ID,
+		rs.WithDescription(extension.Type),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func newExtensionBuilder(c *client.RingCentralClient, resourceType *v2.ResourceType, extensionTypes ...string) *extensionBuilder {
+	return &extensionBuilder{
+		resourceType:   resourceType,
+		client:         c,
+		extensionTypes: extensionTypes,
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"strconv"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
 )
 
 func getToken(pToken *pagination.Token, resourceType *v2.ResourceType) (*pagination.Bag, int, error) {
@@ -49,3 +51,33 @@ func unmarshalSkipToken(token *pagination.Token) (int32, *pagination.Bag, error)
 
 	return ret, b, nil
 }
+
+// newRoleGrant returns the grant of the account wide assignment of the role to the principal.
+func newRoleGrant(roleID string, principalID *v2.ResourceId) *v2.Grant {
+	roleResource := &v2.Resource{
+		Id: &v2.ResourceId{
+			ResourceType: roleResourceType.Id,
+			Resource:     roleID,
+		},
+	}
+
+	return grant.NewGrant(roleResource, rolePermissionName, principalID)
+}
+
+// newUserResourceID returns the ID of the user resource that represents the extension with the given ID.
+func newUserResourceID(extensionID string) *v2.ResourceId {
+	return &v2.ResourceId{
+		ResourceType: userResourceType.Id,
+		Resource:     extensionID,
+	}
+}
+
+// rateLimitAnnotations returns the annotations describing the rate limit reported by the platform, if the response included it.
+func rateLimitAnnotations(rateLimit *v2.RateLimitDescription) annotations.Annotations {
+	annos := annotations.Annotations{}
+	if rateLimit != nil {
+		annos.WithRateLimiting(rateLimit)
+	}
+
+	return annos
+}
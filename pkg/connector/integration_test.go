@@ -46,7 +46,7 @@ func TestUserBuilder_List(t *testing.T) {
 		t.Fatal(message)
 	}
 
-	b := newUserBuilder(c)
+	b := newUserBuilder(c, false, UserSyncModeExtension, newRevokeGuard(c, DefaultProtectedRoles, false), DeprovisioningPolicyDisable, false)
 
 	var users []*v2.Resource
 	paginationToken := &pagination.Token{
@@ -91,7 +91,7 @@ func TestRoleBuilder_List(t *testing.T) {
 		t.Fatal(message)
 	}
 
-	b := newRoleBuilder(c)
+	b := newRoleBuilder(c, newRevokeGuard(c, DefaultProtectedRoles, false))
 
 	var roles []*v2.Resource
 	paginationToken := &pagination.Token{
@@ -121,8 +121,18 @@ func TestRoleBuilder_Entitlements(t *testing.T) {
 	}
 
 	var entitlements []*v2.Entitlement
-	c, _ := client.New(ctx)
-	b := newRoleBuilder(c)
+	// The client must be authenticated since the site scoped entitlements of the roles require the list of sites.
+	c, err := client.New(
+		ctx,
+		client.WithClientID(rcClientID),
+		client.WithClientSecret(rcClientSecret),
+		client.WithJWT(rcJWT),
+	)
+	if err != nil {
+		message = fmt.Sprintf("error creating the client: %v", err)
+		t.Fatal(message)
+	}
+	b := newRoleBuilder(c, newRevokeGuard(c, DefaultProtectedRoles, false))
 
 	for _, role := range rolesCache {
 		entitlementResource, _, _, err := b.Entitlements(ctx, role, nil)
@@ -136,3 +146,121 @@ func TestRoleBuilder_Entitlements(t *testing.T) {
 
 	assert.NotNil(t, entitlements)
 }
+
+// TestCallQueueBuilder_List tests the List function for Call Queue Resources.
+func TestCallQueueBuilder_List(t *testing.T) {
+	if rcClientID == "" {
+		t.Fatal("rcClientID env variable is required")
+	}
+	if rcClientSecret == "" {
+		t.Fatal("rcClientSecret env variable is required")
+	}
+	if rcJWT == "" {
+		t.Fatal("rcJWT env variable is required")
+	}
+
+	c, err := client.New(
+		ctx,
+		client.WithClientID(rcClientID),
+		client.WithClientSecret(rcClientSecret),
+		client.WithJWT(rcJWT),
+	)
+	if err != nil {
+		message = fmt.Sprintf("error creating the client: %v", err)
+		t.Fatal(message)
+	}
+
+	b := newCallQueueBuilder(c, false)
+
+	var callQueues []*v2.Resource
+	paginationToken := &pagination.Token{
+		Size: 5, Token: "",
+	}
+	for {
+		callQueueResources, nextPageToken, _, err := b.List(ctx, parentResourceID, paginationToken)
+		if err != nil {
+			message = fmt.Sprintf("error listing call queues: %v", err)
+			t.Fatal(message)
+		}
+		callQueues = append(callQueues, callQueueResources...)
+		if nextPageToken == "" {
+			break
+		}
+		paginationToken.Token = nextPageToken
+	}
+
+	assert.NotNil(t, callQueues)
+}
+
+// TestConnector_Validate tests that the credentials are accepted and hold the permissions required by sync and provisioning.
+func TestConnector_Validate(t *testing.T) {
+	if rcClientID == "" {
+		t.Fatal("rcClientID env variable is required")
+	}
+	if rcClientSecret == "" {
+		t.Fatal("rcClientSecret env variable is required")
+	}
+	if rcJWT == "" {
+		t.Fatal("rcJWT env variable is required")
+	}
+
+	cb, err := New(ctx, Config{
+		ClientID:     rcClientID,
+		ClientSecret: rcClientSecret,
+		AuthMode:     AuthModeJWT,
+		JWT:          rcJWT,
+		Provisioning: true,
+	})
+	if err != nil {
+		message = fmt.Sprintf("error creating the connector: %v", err)
+		t.Fatal(message)
+	}
+
+	_, err = cb.Validate(ctx)
+	assert.Nil(t, err)
+}
+
+// TestPermissionBuilder_List tests the List function for Permission Resources.
+func TestPermissionBuilder_List(t *testing.T) {
+	if rcClientID == "" {
+		t.Fatal("rcClientID env variable is required")
+	}
+	if rcClientSecret == "" {
+		t.Fatal("rcClientSecret env variable is required")
+	}
+	if rcJWT == "" {
+		t.Fatal("rcJWT env variable is required")
+	}
+
+	c, err := client.New(
+		ctx,
+		client.WithClientID(rcClientID),
+		client.WithClientSecret(rcClientSecret),
+		client.WithJWT(rcJWT),
+	)
+	if err != nil {
+		message = fmt.Sprintf("error creating the client: %v", err)
+		t.Fatal(message)
+	}
+
+	b := newPermissionBuilder(c)
+
+	var permissions []*v2.Resource
+	paginationToken := &pagination.Token{
+		Size: 50, Token: "",
+	}
+	for {
+		permissionResources, nextPageToken, _, err := b.List(ctx, parentResourceID, paginationToken)
+		if err != nil {
+			message = fmt.Sprintf("error listing permissions: %v", err)
+			t.Fatal(message)
+		}
+		permissions = append(permissions, permissionResources...)
+		if nextPageToken == "" {
+			break
+		}
+		paginationToken.Token = nextPageToken
+	}
+
+	assert.NotNil(t, permissions)
+}
@@ -0,0 +1,140 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-ringcentral/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const permissionAssignedEntitlement = "assigned"
+
+type permissionBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.RingCentralClient
+}
+
+func (b *permissionBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return permissionResourceType
+}
+
+// List returns the permissions of the platform dictionary as resource objects.
+func (b *permissionBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var permissionResources []*v2.Resource
+
+	bag, pageToken, err := getToken(pToken, permissionResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	categories, err := b.listCategories(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	permissions, nextPageToken, rateLimit, err := b.client.ListPermissions(ctx, client.PageOptions{
+		Page:    pageToken,
+		PerPage: pToken.Size,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, permission := range permissions {
+		permissionResource, err := parseIntoPermissionResource(permission, categories[permission.Category.ID])
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		permissionResources = append(permissionResources, permissionResource)
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return permissionResources, nextPageToken, rateLimitAnnotations(rateLimit), nil
+}
+
+// Entitlements returns the entitlement of the roles that hold the permission.
+func (b *permissionBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	assignedOptions := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(roleResourceType),
+		entitlement.WithDisplayName(fmt.Sprintf("%s Permission", resource.DisplayName)),
+		entitlement.WithDescription(resource.Description),
+	}
+
+	return []*v2.Entitlement{
+		entitlement.NewPermissionEntitlement(resource, permissionAssignedEntitlement, assignedOptions...),
+	}, "", nil, nil
+}
+
+/*
+Grants function isn't implemented here because they are build in the Grants function of the Roles,
+since the platform only lists the permissions of each role.
+*/
+func (b *permissionBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// listCategories returns the display name of each permission category by its ID. It's read through the response cache of the client,
+// so the pages of permissions don't request it again while new or renamed categories still show up on the following syncs.
+func (b *permissionBuilder) listCategories(ctx context.Context) (map[string]string, error) {
+	categories, err := b.client.ListAllPermissionCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryNames := make(map[string]string, len(categories))
+	for _, category := range categories {
+		categoryNames[category.ID] = category.DisplayName
+	}
+
+	return categoryNames, nil
+}
+
+// parseIntoPermissionResource - This function parses a Permission of the dictionary into a Resource, describing the category it belongs to.
+func parseIntoPermissionResource(permission client.Permission, categoryName string) (*v2.Resource, error) {
+	displayName := permission.DisplayName
+	if displayName == "" {
+		displayName = permission.ID
+	}
+
+	description := permission.Description
+	switch {
+	case categoryName != "" && description == "":
+		description = categoryName
+	case categoryName != "":
+		description = fmt.Sprintf("%s (%s)", description, categoryName)
+	}
+
+	ret, err := rs.NewResource(
+		displayName,
+		permissionResourceType,
+		permission.ID,
+		rs.WithDescription(description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func newPermissionBuilder(c *client.RingCentralClient) *permissionBuilder {
+	return &permissionBuilder{
+		resourceType: permissionResourceType,
+		client:       c,
+	}
+}
@@ -1,6 +1,7 @@
 package connector
 
 import (
+	"github.com/conductorone/baton-ringcentral/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 )
 
@@ -15,3 +16,61 @@ var roleResourceType = &v2.ResourceType{
 	DisplayName: "Role",
 	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_ROLE},
 }
+
+// permissionResourceType represents the permissions of the dictionary of the platform, granted to the roles that hold them.
+var permissionResourceType = &v2.ResourceType{
+	Id:          "permission",
+	DisplayName: "Permission",
+}
+
+var callQueueResourceType = &v2.ResourceType{
+	Id:          "call_queue",
+	DisplayName: "Call Queue",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
+}
+
+var siteResourceType = &v2.ResourceType{
+	Id:          "site",
+	DisplayName: "Site",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
+}
+
+// The following resource types represent the rest of the extensions of the platform that don't belong to a person.
+
+var ivrMenuResourceType = &v2.ResourceType{
+	Id:          "ivr_menu",
+	DisplayName: "IVR Menu",
+}
+
+var sharedLinesGroupResourceType = &v2.ResourceType{
+	Id:          "shared_lines_group",
+	DisplayName: "Shared Lines Group",
+}
+
+var pagingOnlyResourceType = &v2.ResourceType{
+	Id:          "paging_only_group",
+	DisplayName: "Paging Only Group",
+}
+
+var parkLocationResourceType = &v2.ResourceType{
+	Id:          "park_location",
+	DisplayName: "Park Location",
+}
+
+var limitedExtensionResourceType = &v2.ResourceType{
+	Id:          "limited_extension",
+	DisplayName: "Limited Extension",
+}
+
+// extensionResourceTypes maps the type of an Extension into the resource type that represents it.
+var extensionResourceTypes = map[string]*v2.ResourceType{
+	client.ExtensionTypeUser:             userResourceType,
+	client.ExtensionTypeDigitalUser:      userResourceType,
+	client.ExtensionTypeVirtualUser:      userResourceType,
+	client.ExtensionTypeDepartment:       callQueueResourceType,
+	client.ExtensionTypeIvrMenu:          ivrMenuResourceType,
+	client.ExtensionTypeSharedLinesGroup: sharedLinesGroupResourceType,
+	client.ExtensionTypePagingOnly:       pagingOnlyResourceType,
+	client.ExtensionTypeParkLocation:     parkLocationResourceType,
+	client.ExtensionTypeLimited:          limitedExtensionResourceType,
+}
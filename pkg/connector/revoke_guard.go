@@ -0,0 +1,195 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/conductorone/baton-ringcentral/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultProtectedRoles are the roles guarded by default, revoking them from the wrong user locks the connector out of the account.
+var DefaultProtectedRoles = []string{"Super Admin"}
+
+/*
+revokeGuard refuses the revokes of protected roles that could lock the connector out of the account: revoking them from the extension
+the connector authenticates as, or from their last enabled holder. Protected roles are matched by ID or by display name.
+The checks are skipped when the unsafe revokes are allowed. The same checks apply to the deprovisioning of users, see checkDeprovision.
+*/
+type revokeGuard struct {
+	client             *client.RingCentralClient
+	protectedRoles     []string
+	allowUnsafeRevokes bool
+
+	// The extension the connector authenticates as is requested only once, on the first revoke of a protected role.
+	currentExtensionMtx sync.Mutex
+	currentExtensionID  string
+}
+
+/*
+revokeCheck returns the check of the revoke of the role from the extension, or nil when the revoke isn't guarded. Revoking a protected role
+from the extension the connector authenticates as is refused right away, with a FailedPrecondition error. The returned check refuses the revoke
+from the last enabled holder of the role, it's passed to UpdateUserRoles so the holders are looked up in the same critical section as the write.
+*/
+func (g *revokeGuard) revokeCheck(ctx context.Context, role *v2.Resource, extensionID string, siteID string) (client.RevokeCheck, error) {
+	// Site restricted assignments never grant access to the whole account, and a site revoke never touches the account wide assignment
+	// of the role, so the holders that keep the connector in the account aren't affected by them.
+	if g.allowUnsafeRevokes || siteID != "" {
+		return nil, nil
+	}
+
+	roleName, err := g.getRoleName(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	if !g.isProtectedRole(role.Id.Resource, roleName) {
+		return nil, nil
+	}
+
+	currentExtensionID, err := g.getCurrentExtensionID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if extensionID == currentExtensionID {
+		return nil, status.Errorf(
+			codes.FailedPrecondition,
+			"ringcentral-connector: refusing to revoke the protected role '%s' from the extension with ID: '%s', the connector authenticates as it",
+			roleName,
+			extensionID,
+		)
+	}
+
+	return func(ctx context.Context) error {
+		hasOtherHolder, err := g.client.HasOtherRoleHolder(ctx, role.Id.Resource, extensionID)
+		if err != nil {
+			return err
+		}
+
+		if !hasOtherHolder {
+			return status.Errorf(
+				codes.FailedPrecondition,
+				"ringcentral-connector: refusing to revoke the protected role '%s' from the extension with ID: '%s', it's the last enabled user holding it",
+				roleName,
+				extensionID,
+			)
+		}
+
+		return nil
+	}, nil
+}
+
+/*
+checkDeprovision returns the check of the disable or the delete of the extension, or nil when the deprovisioning isn't guarded.
+Deprovisioning the extension the connector authenticates as is always refused right away, with a FailedPrecondition error. The returned check
+refuses deprovisioning the last enabled holder of a protected role, it's passed to DeprovisionExtension so the roles and the holders
+are looked up in the same critical section as the write.
+*/
+func (g *revokeGuard) checkDeprovision(ctx context.Context, extensionID string) (client.RevokeCheck, error) {
+	currentExtensionID, err := g.getCurrentExtensionID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if extensionID == currentExtensionID {
+		return nil, status.Errorf(
+			codes.FailedPrecondition,
+			"ringcentral-connector: refusing to deprovision the extension with ID: '%s', the connector authenticates as it",
+			extensionID,
+		)
+	}
+
+	if g.allowUnsafeRevokes {
+		return nil, nil
+	}
+
+	return func(ctx context.Context) error {
+		userRoles, err := g.client.GetCurrentUserRoles(ctx, extensionID)
+		if err != nil {
+			return err
+		}
+
+		for _, userRole := range userRoles {
+			if userRole.SiteRestricted {
+				continue
+			}
+
+			roleName, err := g.getRoleName(ctx, &v2.Resource{Id: &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: userRole.Id}})
+			if err != nil {
+				return err
+			}
+
+			if !g.isProtectedRole(userRole.Id, roleName) {
+				continue
+			}
+
+			hasOtherHolder, err := g.client.HasOtherRoleHolder(ctx, userRole.Id, extensionID)
+			if err != nil {
+				return err
+			}
+
+			if !hasOtherHolder {
+				return status.Errorf(
+					codes.FailedPrecondition,
+					"ringcentral-connector: refusing to deprovision the extension with ID: '%s', it's the last enabled user holding the protected role '%s'",
+					extensionID,
+					roleName,
+				)
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+func (g *revokeGuard) isProtectedRole(roleID string, roleName string) bool {
+	return slices.ContainsFunc(g.protectedRoles, func(protectedRole string) bool {
+		return protectedRole == roleID || strings.EqualFold(protectedRole, roleName)
+	})
+}
+
+// getRoleName returns the display name of the role, which isn't always part of the resource of the grants being revoked.
+func (g *revokeGuard) getRoleName(ctx context.Context, role *v2.Resource) (string, error) {
+	if role.DisplayName != "" {
+		return role.DisplayName, nil
+	}
+
+	platformRole, err := g.client.GetRole(ctx, role.Id.Resource)
+	if err != nil {
+		return "", err
+	}
+
+	return platformRole.DisplayName, nil
+}
+
+func (g *revokeGuard) getCurrentExtensionID(ctx context.Context) (string, error) {
+	g.currentExtensionMtx.Lock()
+	defer g.currentExtensionMtx.Unlock()
+
+	if g.currentExtensionID != "" {
+		return g.currentExtensionID, nil
+	}
+
+	extension, err := g.client.GetCurrentExtension(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	g.currentExtensionID = strconv.FormatInt(extension.ID, 10)
+
+	return g.currentExtensionID, nil
+}
+
+func newRevokeGuard(c *client.RingCentralClient, protectedRoles []string, allowUnsafeRevokes bool) *revokeGuard {
+	return &revokeGuard{
+		client:             c,
+		protectedRoles:     protectedRoles,
+		allowUnsafeRevokes: allowUnsafeRevokes,
+	}
+}
@@ -0,0 +1,82 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conductorone/baton-ringcentral/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestRevokeGuard_RevokeCheck tests which revokes of protected roles are refused. The connector authenticates as the extension 1,
+// the Super Admin role (ID 1) is held by the extensions 1 and 2, and the Compliance role (ID 2) by the extension 2 and the disabled extension 3.
+func TestRevokeGuard_RevokeCheck(t *testing.T) {
+	responses := map[string]string{
+		"/restapi/v1.0/account/~/extension/~":               `{"id":1,"type":"User","status":"Enabled"}`,
+		"/restapi/v1.0/account/~/extension":                 `{"records":[{"id":1,"status":"Enabled"},{"id":2,"status":"Enabled"},{"id":3,"status":"Disabled"}]}`,
+		"/restapi/v1.0/account/~/extension/1/assigned-role": `{"records":[{"id":"1"}]}`,
+		"/restapi/v1.0/account/~/extension/2/assigned-role": `{"records":[{"id":"1"},{"id":"2"}]}`,
+		"/restapi/v1.0/account/~/extension/3/assigned-role": `{"records":[{"id":"2"}]}`,
+		"/restapi/v1.0/account/~/user-role/1":               `{"id":"1","displayName":"Super Admin"}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+	require.NoError(t, err)
+
+	superAdmin := &v2.Resource{Id: &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "1"}, DisplayName: "Super Admin"}
+	compliance := &v2.Resource{Id: &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "2"}, DisplayName: "Compliance"}
+	auditor := &v2.Resource{Id: &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "3"}, DisplayName: "Auditor"}
+	unnamedSuperAdmin := &v2.Resource{Id: &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "1"}}
+
+	tests := []struct {
+		name        string
+		allowUnsafe bool
+		role        *v2.Resource
+		extensionID string
+		siteID      string
+		refused     bool
+	}{
+		{name: "unprotected role", role: auditor, extensionID: "1"},
+		{name: "protected role from the connector", role: superAdmin, extensionID: "1", refused: true},
+		{name: "protected role without name from the connector", role: unnamedSuperAdmin, extensionID: "1", refused: true},
+		{name: "protected role with other holders", role: superAdmin, extensionID: "2"},
+		{name: "protected role by ID from its last enabled holder", role: compliance, extensionID: "2", refused: true},
+		{name: "protected role from a site", role: superAdmin, extensionID: "1", siteID: "berlin"},
+		{name: "unsafe revokes allowed", allowUnsafe: true, role: superAdmin, extensionID: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := newRevokeGuard(c, []string{"super admin", "2"}, tt.allowUnsafe)
+
+			checkRevoke, err := guard.revokeCheck(context.Background(), tt.role, tt.extensionID, tt.siteID)
+			if err == nil && checkRevoke != nil {
+				err = checkRevoke(context.Background())
+			}
+			if !tt.refused {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		})
+	}
+}
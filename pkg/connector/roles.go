@@ -3,22 +3,33 @@ package connector
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/conductorone/baton-ringcentral/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-const rolePermissionName = "assigned"
+const (
+	rolePermissionName = "assigned"
+	roleSitePrefix     = "site:"
+
+	// roleTemplateProfileKey is the field of the role profile holding the ID of the role whose permissions are cloned into a new custom role.
+	roleTemplateProfileKey = "template_role_id"
+)
 
 type roleBuilder struct {
 	client       *client.RingCentralClient
 	resourceType *v2.ResourceType
+	revokeGuard  *revokeGuard
 }
 
 func (b *roleBuilder) ResourceType(_ context.Context) *v2.ResourceType {
@@ -33,7 +44,7 @@ func (b *roleBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagina
 		return nil, "", nil, err
 	}
 
-	roles, nextPageToken, err := b.client.ListAllAvailableRoles(ctx, client.PageOptions{
+	roles, nextPageToken, rateLimit, err := b.client.ListAllAvailableRoles(ctx, client.PageOptions{
 		Page:    pageToken,
 		PerPage: pToken.Size,
 	})
@@ -60,10 +71,14 @@ func (b *roleBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagina
 		return nil, "", nil, err
 	}
 
-	return roleResources, nextPageToken, nil, nil
+	return roleResources, nextPageToken, rateLimitAnnotations(rateLimit), nil
 }
 
-func (b *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+/*
+Entitlements returns the account wide assignment of the role.
+Site compatible roles also get one entitlement per site, representing the assignment of the role restricted to that site.
+*/
+func (b *roleBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var roleEntitlements []*v2.Entitlement
 
 	assigmentOptions := []entitlement.EntitlementOption{
@@ -74,15 +89,74 @@ func (b *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *
 
 	roleEntitlements = append(roleEntitlements, entitlement.NewPermissionEntitlement(resource, rolePermissionName, assigmentOptions...))
 
+	if !isSiteCompatibleRole(resource) {
+		return roleEntitlements, "", nil, nil
+	}
+
+	// The sites are read again for every role, the response cache of the client spares the requests while still picking up new sites.
+	sites, err := b.client.ListAllSites(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, site := range sites {
+		siteAssigmentOptions := []entitlement.EntitlementOption{
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDescription(fmt.Sprintf("%s, restricted to the %s site", resource.DisplayName, site.Name)),
+			entitlement.WithDisplayName(fmt.Sprintf("%s (%s)", resource.DisplayName, site.Name)),
+		}
+
+		roleEntitlements = append(roleEntitlements, entitlement.NewPermissionEntitlement(resource, siteRoleEntitlementName(site.ID), siteAssigmentOptions...))
+	}
+
 	return roleEntitlements, "", nil, nil
 }
 
 /*
-Grants function isn't implemented here because they are build in the Grants function of the Users.
-This was made like this since it was convenient considering the data model of the platform.
+Grants returns the permissions held by the role. They're expanded to the holders of the role, either account wide or restricted to a site,
+so the users that effectively hold each permission can be told. The assignments of the role are built in the Grants function of the Users,
+since it was convenient considering the data model of the platform.
 */
-func (b *roleBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+func (b *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	var permissionGrants []*v2.Grant
+
+	role, err := b.client.GetRole(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	expandableEntitlementIDs := []string{entitlement.NewEntitlementID(resource, rolePermissionName)}
+	if isSiteCompatibleRole(resource) {
+		sites, err := b.client.ListAllSites(ctx)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		for _, site := range sites {
+			expandableEntitlementIDs = append(expandableEntitlementIDs, entitlement.NewEntitlementID(resource, siteRoleEntitlementName(site.ID)))
+		}
+	}
+
+	for _, permission := range role.Permissions {
+		permissionResource := &v2.Resource{
+			Id: &v2.ResourceId{
+				ResourceType: permissionResourceType.Id,
+				Resource:     permission.ID,
+			},
+		}
+
+		permissionGrants = append(permissionGrants, grant.NewGrant(
+			permissionResource,
+			permissionAssignedEntitlement,
+			resource.Id,
+			grant.WithAnnotation(&v2.GrantExpandable{
+				EntitlementIds:  expandableEntitlementIDs,
+				ResourceTypeIds: []string{userResourceType.Id},
+			}),
+		))
+	}
+
+	return permissionGrants, "", nil, nil
 }
 
 func (b *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
@@ -95,18 +169,121 @@ func (b *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitle
 	}
 
 	roleID := entitlement.Resource.Id.Resource
-	err := b.client.UpdateUserRoles(ctx, principal, roleID, false)
+	siteID, _ := siteIDFromRoleEntitlement(entitlement)
+	roleUpdate, err := b.client.UpdateUserRoles(ctx, principal, roleID, siteID, false, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	if !roleUpdate.Updated {
+		l.Debug("ringcentral-connector: role is already assigned to the user",
+			zap.String("role_id", roleID),
+			zap.String("site_id", siteID),
+			zap.String("principal_id", principal.Id.Resource))
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
 	return nil, nil
 }
 
 func (b *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
 	roleID := grant.Entitlement.Resource.Id.Resource
+	siteID, _ := siteIDFromRoleEntitlement(grant.Entitlement)
+
+	checkRevoke, err := b.revokeGuard.revokeCheck(ctx, grant.Entitlement.Resource, grant.Principal.Id.Resource, siteID)
+	if err != nil {
+		return nil, err
+	}
+
+	roleUpdate, err := b.client.UpdateUserRoles(ctx, grant.Principal, roleID, siteID, true, checkRevoke)
+	if err != nil {
+		return nil, err
+	}
+
+	if !roleUpdate.Updated {
+		l.Debug("ringcentral-connector: role is not assigned to the user",
+			zap.String("role_id", roleID),
+			zap.String("site_id", siteID),
+			zap.String("principal_id", grant.Principal.Id.Resource))
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	// The revoked role was the only role of the user, the grant of the fallback role assigned in its place is reported back.
+	if roleUpdate.FallbackRoleID != "" {
+		l.Info("ringcentral-connector: revoked the only role of the user, the fallback role was assigned in its place",
+			zap.String("role_id", roleID),
+			zap.String("fallback_role_id", roleUpdate.FallbackRoleID),
+			zap.String("principal_id", grant.Principal.Id.Resource))
+		return annotations.New(newRoleGrant(roleUpdate.FallbackRoleID, grant.Principal.Id)), nil
+	}
+
+	return nil, nil
+}
+
+/*
+Create creates a custom role named after the display name of the resource, holding the permissions of the template role.
+The ID of the template role is read from the 'template_role_id' field of the role profile, and the new role is site compatible if the template is.
+*/
+func (b *roleBuilder) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	if resource.DisplayName == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "ringcentral-connector: the display name of the role is required")
+	}
+
+	templateRoleID := getTemplateRoleID(resource)
+	if templateRoleID == "" {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "ringcentral-connector: the '%s' field of the role profile is required", roleTemplateProfileKey)
+	}
+
+	templateRole, err := b.client.GetRole(ctx, templateRoleID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	role, err := b.client.CreateRole(ctx, resource.DisplayName, resource.Description, *templateRole)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctxzap.Extract(ctx).Info("ringcentral-connector: created custom role",
+		zap.String("role_id", role.Id),
+		zap.String("template_role_id", templateRoleID))
+
+	roleResource, err := parseIntoRoleResource(*role)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	err := b.client.UpdateUserRoles(ctx, grant.Principal, roleID, true)
+	return roleResource, nil, nil
+}
+
+// Delete deletes a custom role. Predefined roles, and roles still assigned to any user, are refused.
+func (b *roleBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	role, err := b.client.GetRole(ctx, resourceId.Resource)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			l.Debug("ringcentral-connector: role is already deleted", zap.String("role_id", resourceId.Resource))
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !role.Custom {
+		return nil, status.Errorf(codes.FailedPrecondition, "ringcentral-connector: refusing to delete the role '%s', only custom roles can be deleted", role.DisplayName)
+	}
+
+	hasAssignees, err := b.client.HasRoleAssignees(ctx, role.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasAssignees {
+		return nil, status.Errorf(codes.FailedPrecondition, "ringcentral-connector: refusing to delete the role '%s', it's still assigned to some users", role.DisplayName)
+	}
+
+	err = b.client.DeleteRole(ctx, role.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -116,12 +293,13 @@ func (b *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.
 
 func parseIntoRoleResource(role client.Role) (*v2.Resource, error) {
 	profile := map[string]interface{}{
-		"role_id":      role.Id,
-		"description":  role.Description,
-		"display_name": role.DisplayName,
-		"scope":        role.Scope,
-		"hidden":       role.Hidden,
-		"custom":       role.Custom,
+		"role_id":         role.Id,
+		"description":     role.Description,
+		"display_name":    role.DisplayName,
+		"scope":           role.Scope,
+		"hidden":          role.Hidden,
+		"custom":          role.Custom,
+		"site_compatible": role.SiteCompatible,
 	}
 
 	roleTraits := []rs.RoleTraitOption{
@@ -141,9 +319,57 @@ func parseIntoRoleResource(role client.Role) (*v2.Resource, error) {
 	return ret, nil
 }
 
-func newRoleBuilder(c *client.RingCentralClient) *roleBuilder {
+// isSiteCompatibleRole reports whether the role can be assigned restricted to a site, based on the profile of the role resource.
+func isSiteCompatibleRole(resource *v2.Resource) bool {
+	roleTrait, err := rs.GetRoleTrait(resource)
+	if err != nil {
+		return false
+	}
+
+	siteCompatible, ok := roleTrait.GetProfile().GetFields()["site_compatible"]
+	if !ok {
+		return false
+	}
+
+	return siteCompatible.GetBoolValue()
+}
+
+// getTemplateRoleID returns the ID of the template role set on the profile of the role resource, if any.
+func getTemplateRoleID(resource *v2.Resource) string {
+	roleTrait, err := rs.GetRoleTrait(resource)
+	if err != nil {
+		return ""
+	}
+
+	templateRoleID, ok := rs.GetProfileStringValue(roleTrait.GetProfile(), roleTemplateProfileKey)
+	if !ok {
+		return ""
+	}
+
+	return templateRoleID
+}
+
+// siteRoleEntitlementName returns the name of the entitlement that assigns a role restricted to the given site.
+func siteRoleEntitlementName(siteID string) string {
+	return rolePermissionName + ":" + roleSitePrefix + siteID
+}
+
+// siteIDFromRoleEntitlement returns the ID of the site the role entitlement is restricted to, if any.
+func siteIDFromRoleEntitlement(e *v2.Entitlement) (string, bool) {
+	prefix := entitlement.NewEntitlementID(e.Resource, siteRoleEntitlementName(""))
+
+	siteID, ok := strings.CutPrefix(e.Id, prefix)
+	if !ok || siteID == "" {
+		return "", false
+	}
+
+	return siteID, true
+}
+
+func newRoleBuilder(c *client.RingCentralClient, guard *revokeGuard) *roleBuilder {
 	return &roleBuilder{
 		resourceType: roleResourceType,
 		client:       c,
+		revokeGuard:  guard,
 	}
 }
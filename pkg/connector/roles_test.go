@@ -0,0 +1,384 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-ringcentral/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+fakeRoles serves the roles of an account with the predefined role 1, the custom role 2 assigned to the disabled extension 1
+restricted to a site, and the unassigned custom role 3. Created roles are recorded, and deleted ones stop being served.
+*/
+type fakeRoles struct {
+	mtx     sync.Mutex
+	created []client.RoleCreateBody
+	deleted []string
+}
+
+func (f *fakeRoles) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	responses := map[string]string{
+		"/restapi/v1.0/account/~/extension":                 `{"records":[{"id":1,"status":"Disabled"},{"id":2,"status":"Enabled"}]}`,
+		"/restapi/v1.0/account/~/extension/1/assigned-role": `{"records":[{"id":"2","siteRestricted":true,"sites":[{"id":"berlin"}]}]}`,
+		"/restapi/v1.0/account/~/extension/2/assigned-role": `{"records":[{"id":"1"}]}`,
+		"/restapi/v1.0/account/~/user-role/1": `{"id":"1","displayName":"Standard","siteCompatible":true,` +
+			`"permissions":[{"uri":"https://platform/permission/ReadCallLog","id":"ReadCallLog"},{"id":"EditPresence"}]}`,
+		"/restapi/v1.0/account/~/user-role/2": `{"id":"2","displayName":"Front Desk","custom":true}`,
+		"/restapi/v1.0/account/~/user-role/3": `{"id":"3","displayName":"Auditor","custom":true}`,
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/restapi/v1.0/account/~/user-role":
+		var body client.RoleCreateBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.created = append(f.created, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(client.Role{
+			Id:             "100",
+			DisplayName:    body.DisplayName,
+			Description:    body.Description,
+			Custom:         true,
+			SiteCompatible: body.SiteCompatible,
+		})
+		return
+
+	case r.Method == http.MethodDelete:
+		f.deleted = append(f.deleted, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	response, ok := responses[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(response))
+}
+
+// TestRoleBuilder_Create tests that custom roles are created with the permissions of the template role.
+func TestRoleBuilder_Create(t *testing.T) {
+	fake := &fakeRoles{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+	require.NoError(t, err)
+
+	b := newRoleBuilder(c, newRevokeGuard(c, DefaultProtectedRoles, false))
+
+	resource, err := rs.NewRoleResource("Night Shift", roleResourceType, "", []rs.RoleTraitOption{
+		rs.WithRoleProfile(map[string]interface{}{roleTemplateProfileKey: "1"}),
+	})
+	require.NoError(t, err)
+	resource.Description = "Standard users working at night"
+
+	created, _, err := b.Create(context.Background(), resource)
+	require.NoError(t, err)
+	assert.Equal(t, "100", created.Id.Resource)
+	assert.Equal(t, "Night Shift", created.DisplayName)
+	assert.True(t, isSiteCompatibleRole(created))
+
+	require.Len(t, fake.created, 1)
+	assert.Equal(t, client.RoleCreateBody{
+		DisplayName:    "Night Shift",
+		Description:    "Standard users working at night",
+		SiteCompatible: true,
+		Permissions:    []client.PermissionReference{{ID: "ReadCallLog"}, {ID: "EditPresence"}},
+	}, fake.created[0])
+
+	t.Run("without template", func(t *testing.T) {
+		withoutTemplate, err := rs.NewRoleResource("Night Shift", roleResourceType, "", nil)
+		require.NoError(t, err)
+
+		_, _, err = b.Create(context.Background(), withoutTemplate)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+// TestRoleBuilder_Delete tests that only the custom roles without assignees are deleted.
+func TestRoleBuilder_Delete(t *testing.T) {
+	tests := []struct {
+		name    string
+		roleID  string
+		code    codes.Code
+		deleted bool
+	}{
+		{name: "predefined role", roleID: "1", code: codes.FailedPrecondition},
+		{name: "custom role with assignees", roleID: "2", code: codes.FailedPrecondition},
+		{name: "unassigned custom role", roleID: "3", code: codes.OK, deleted: true},
+		{name: "missing role", roleID: "4", code: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeRoles{}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+			require.NoError(t, err)
+
+			b := newRoleBuilder(c, newRevokeGuard(c, DefaultProtectedRoles, false))
+
+			_, err = b.Delete(context.Background(), &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: tt.roleID})
+			assert.Equal(t, tt.code, status.Code(err))
+
+			if tt.deleted {
+				assert.Equal(t, []string{"/restapi/v1.0/account/~/user-role/" + tt.roleID}, fake.deleted)
+			} else {
+				assert.Empty(t, fake.deleted)
+			}
+		})
+	}
+}
+
+/*
+fakeRoleHolders serves the users of an account, where the connector authenticates as the extension 1 and the rest hold the Super Admin
+role (ID 1) along with the Standard role (ID 2), the default role of the account. The roles are updated by the bulk-assign requests
+and by the writes of the roles lists, and the extensions are disabled or deleted by the deprovisioning requests.
+*/
+type fakeRoleHolders struct {
+	mtx      sync.Mutex
+	statuses map[string]string
+	roles    map[string][]string
+}
+
+func newFakeRoleHolders(holders ...string) *fakeRoleHolders {
+	f := &fakeRoleHolders{
+		statuses: map[string]string{"1": client.ExtensionStatusEnabled},
+		roles:    map[string][]string{"1": {"1"}},
+	}
+	for _, holder := range holders {
+		f.statuses[holder] = client.ExtensionStatusEnabled
+		f.roles[holder] = []string{"1", "2"}
+	}
+
+	return f
+}
+
+func (f *fakeRoleHolders) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	urlPath := strings.TrimPrefix(r.URL.Path, "/restapi/v1.0/account/~")
+
+	switch {
+	case urlPath == "/extension/~":
+		_, _ = w.Write([]byte(`{"id":1,"type":"User","status":"Enabled"}`))
+
+	case urlPath == "/user-role/1":
+		_, _ = w.Write([]byte(`{"id":"1","displayName":"Super Admin"}`))
+
+	case urlPath == "/user-role/2", urlPath == "/user-role/default":
+		_, _ = w.Write([]byte(`{"id":"2","displayName":"Standard"}`))
+
+	case urlPath == "/extension":
+		var response client.ExtensionResponse
+		for id, extensionStatus := range f.statuses {
+			extensionID, _ := strconv.ParseInt(id, 10, 64)
+			response.Records = append(response.Records, client.Extension{ID: extensionID, Status: extensionStatus})
+		}
+		_ = json.NewEncoder(w).Encode(response)
+
+	case strings.HasSuffix(urlPath, "/bulk-assign"):
+		var body client.RoleBulkAssignBody
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		roleID := path.Base(path.Dir(urlPath))
+		for _, extensionID := range body.RemovedExtensionIds {
+			f.roles[extensionID] = slices.DeleteFunc(f.roles[extensionID], func(id string) bool { return id == roleID })
+		}
+		for _, extensionID := range body.AddedExtensionIds {
+			f.roles[extensionID] = append(f.roles[extensionID], roleID)
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case strings.HasSuffix(urlPath, "/assigned-role"):
+		extensionID := path.Base(path.Dir(urlPath))
+		if r.Method == http.MethodPut {
+			var body struct {
+				Records []client.AssignedRoleRecord `json:"records"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+
+			f.roles[extensionID] = nil
+			for _, record := range body.Records {
+				f.roles[extensionID] = append(f.roles[extensionID], record.Id)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var records []client.AssignedRoleRecord
+		for _, roleID := range f.roles[extensionID] {
+			records = append(records, client.AssignedRoleRecord{Id: roleID})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"records": records})
+
+	case path.Dir(urlPath) == "/extension":
+		extensionID := path.Base(urlPath)
+		extensionStatus, ok := f.statuses[extensionID]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			var body client.ExtensionStatusUpdateBody
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			f.statuses[extensionID] = body.Status
+			_, _ = w.Write([]byte(`{}`))
+		case http.MethodDelete:
+			delete(f.statuses, extensionID)
+			delete(f.roles, extensionID)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			id, _ := strconv.ParseInt(extensionID, 10, 64)
+			_ = json.NewEncoder(w).Encode(client.Extension{ID: id, Status: extensionStatus})
+		}
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeRoleHolders) disable(extensionID string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.statuses[extensionID] = client.ExtensionStatusDisabled
+}
+
+func (f *fakeRoleHolders) holders(roleID string) []string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	var holders []string
+	for extensionID, roles := range f.roles {
+		if slices.Contains(roles, roleID) {
+			holders = append(holders, extensionID)
+		}
+	}
+	slices.Sort(holders)
+
+	return holders
+}
+
+// TestRoleBuilder_RevokeProtected tests that the last holder of a protected role is looked up when the revoke is written, not when it's requested.
+func TestRoleBuilder_RevokeProtected(t *testing.T) {
+	superAdmin := &v2.Resource{Id: &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "1"}, DisplayName: "Super Admin"}
+	newRevoke := func(extensionID string) *v2.Grant {
+		return &v2.Grant{
+			Entitlement: &v2.Entitlement{Id: entitlement.NewEntitlementID(superAdmin, rolePermissionName), Resource: superAdmin},
+			Principal:   &v2.Resource{Id: newUserResourceID(extensionID)},
+		}
+	}
+
+	t.Run("concurrent revokes from the last holders", func(t *testing.T) {
+		fake := newFakeRoleHolders("2", "3")
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+		require.NoError(t, err)
+
+		// The connector itself doesn't count as a holder, since it must not be the only one left with the role.
+		fake.disable("1")
+
+		b := newRoleBuilder(c, newRevokeGuard(c, DefaultProtectedRoles, false))
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i, extensionID := range []string{"2", "3"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = b.Revoke(context.Background(), newRevoke(extensionID))
+			}()
+		}
+		wg.Wait()
+
+		codesReturned := []codes.Code{status.Code(errs[0]), status.Code(errs[1])}
+		assert.ElementsMatch(t, []codes.Code{codes.OK, codes.FailedPrecondition}, codesReturned)
+		assert.Len(t, slices.DeleteFunc(fake.holders("1"), func(id string) bool { return id == "1" }), 1)
+	})
+
+	t.Run("holder disabled after the sync", func(t *testing.T) {
+		fake := newFakeRoleHolders("2", "3")
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+		require.NoError(t, err)
+
+		fake.disable("1")
+		_, _, _, err = c.ListAllUsers(context.Background(), client.PageOptions{PerPage: client.ItemsPerPage})
+		require.NoError(t, err)
+		fake.disable("3")
+
+		b := newRoleBuilder(c, newRevokeGuard(c, DefaultProtectedRoles, false))
+
+		_, err = b.Revoke(context.Background(), newRevoke("2"))
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Equal(t, []string{"1", "2", "3"}, fake.holders("1"))
+	})
+}
+
+// TestRoleBuilder_RevokeFallback tests that revoking the only role of a user reports the grant of the fallback role assigned in its place.
+func TestRoleBuilder_RevokeFallback(t *testing.T) {
+	fake := newFakeRoleHolders()
+	fake.statuses["4"] = client.ExtensionStatusEnabled
+	fake.roles["4"] = []string{"3"}
+
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+	require.NoError(t, err)
+
+	b := newRoleBuilder(c, newRevokeGuard(c, DefaultProtectedRoles, false))
+
+	auditor := &v2.Resource{Id: &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "3"}, DisplayName: "Auditor"}
+	annos, err := b.Revoke(context.Background(), &v2.Grant{
+		Entitlement: &v2.Entitlement{Id: entitlement.NewEntitlementID(auditor, rolePermissionName), Resource: auditor},
+		Principal:   &v2.Resource{Id: newUserResourceID("4")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4"}, fake.holders("2"))
+
+	fallbackGrant := &v2.Grant{}
+	ok, err := annos.Pick(fallbackGrant)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, entitlement.NewEntitlementID(&v2.Resource{Id: &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "2"}}, rolePermissionName),
+		fallbackGrant.Entitlement.Id)
+	assert.Equal(t, "4", fallbackGrant.Principal.Id.Resource)
+}
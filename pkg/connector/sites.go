@@ -0,0 +1,185 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-ringcentral/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const siteMemberEntitlement = "member"
+
+type siteBuilder struct {
+	resourceType    *v2.ResourceType
+	client          *client.RingCentralClient
+	excludeInactive bool
+}
+
+func (b *siteBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return siteResourceType
+}
+
+// List returns all the sites of the company as resource objects.
+func (b *siteBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var siteResources []*v2.Resource
+
+	bag, pageToken, err := getToken(pToken, siteResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	sites, nextPageToken, rateLimit, err := b.client.ListSites(ctx, client.PageOptions{
+		Page:    pageToken,
+		PerPage: pToken.Size,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, site := range sites {
+		siteResource, err := parseIntoSiteResource(site)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		siteResources = append(siteResources, siteResource)
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return siteResources, nextPageToken, rateLimitAnnotations(rateLimit), nil
+}
+
+// Entitlements returns the membership entitlement of a site. Every extension of the company belongs to exactly one site.
+func (b *siteBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	grantableTo := []*v2.ResourceType{
+		userResourceType,
+		callQueueResourceType,
+		ivrMenuResourceType,
+		sharedLinesGroupResourceType,
+		pagingOnlyResourceType,
+		parkLocationResourceType,
+		limitedExtensionResourceType,
+	}
+
+	memberOptions := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(grantableTo...),
+		entitlement.WithDisplayName(fmt.Sprintf("%s Site Member", resource.DisplayName)),
+		entitlement.WithDescription(fmt.Sprintf("Belongs to the %s site", resource.DisplayName)),
+	}
+
+	return []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(resource, siteMemberEntitlement, memberOptions...),
+	}, "", nil, nil
+}
+
+/*
+Grants returns the extensions that belong to the site. Members of extension types that aren't synced are skipped,
+and so are the inactive users when they're excluded from the sync.
+*/
+func (b *siteBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	var grants []*v2.Grant
+	l := ctxzap.Extract(ctx)
+
+	bag, pageToken, err := getToken(pToken, siteResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	excludedUserIDs, err := listExcludedUserIDs(ctx, b.client, b.excludeInactive)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	members, nextPageToken, rateLimit, err := b.client.ListSiteMembers(ctx, resource.Id.Resource, client.PageOptions{
+		Page:    pageToken,
+		PerPage: pToken.Size,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, member := range members {
+		memberResourceType, ok := extensionResourceTypes[member.Type]
+		if !ok {
+			l.Debug("ringcentral-connector: skipping site member with an unsupported extension type",
+				zap.String("site_id", resource.Id.Resource),
+				zap.String("extension_id", member.ID),
+				zap.String("extension_type", member.Type))
+			continue
+		}
+
+		if excludedUserIDs[member.ID] {
+			continue
+		}
+
+		principalID := &v2.ResourceId{
+			ResourceType: memberResourceType.Id,
+			Resource:     member.ID,
+		}
+		grants = append(grants, grant.NewGrant(resource, siteMemberEntitlement, principalID))
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return grants, nextPageToken, rateLimitAnnotations(rateLimit), nil
+}
+
+// parseIntoSiteResource - This function parses a Site into a Group Resource.
+func parseIntoSiteResource(site client.Site) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"site_id":          site.ID,
+		"name":             site.Name,
+		"extension_number": site.ExtensionNumber,
+		"code":             site.Code,
+		"email":            site.Email,
+	}
+
+	groupTraits := []rs.GroupTraitOption{
+		rs.WithGroupProfile(profile),
+	}
+
+	ret, err := rs.NewGroupResource(
+		site.Name,
+		siteResourceType,
+		site.ID,
+		groupTraits,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func newSiteBuilder(c *client.RingCentralClient, excludeInactive bool) *siteBuilder {
+	return &siteBuilder{
+		resourceType:    siteResourceType,
+		client:          c,
+		excludeInactive: excludeInactive,
+	}
+}
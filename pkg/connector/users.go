@@ -2,18 +2,44 @@ package connector
 
 import (
 	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/conductorone/baton-ringcentral/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Fields of the account creation schema, read from the profile of the account info when a user is created.
+const (
+	accountEmailField           = "email"
+	accountFirstNameField       = "first_name"
+	accountLastNameField        = "last_name"
+	accountExtensionNumberField = "extension_number"
+	accountSiteIDField          = "site_id"
+	accountRoleIDField          = "role_id"
 )
 
 type userBuilder struct {
-	resourceType *v2.ResourceType
-	client       *client.RingCentralClient
+	resourceType    *v2.ResourceType
+	client          *client.RingCentralClient
+	excludeInactive bool
+	// syncMode tells whether the users are read from the extensions of the REST API or from the SCIM API, see UserSyncModeExtension and UserSyncModeSCIM.
+	syncMode    string
+	revokeGuard *revokeGuard
+	// deprovisioningPolicy tells whether deleting a user disables or deletes its extension, see DeprovisioningPolicyDisable and DeprovisioningPolicyDelete.
+	deprovisioningPolicy string
+	reclaimPhoneNumbers  bool
 }
 
 func (b *userBuilder) ResourceType(_ context.Context) *v2.ResourceType {
@@ -25,12 +51,92 @@ func (b *userBuilder) ResourceType(_ context.Context) *v2.ResourceType {
 func (b *userBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
 	var userResources []*v2.Resource
 
+	if b.syncMode == UserSyncModeSCIM {
+		return b.listScimUsers(ctx, pToken)
+	}
+
+	bag, pageToken, err := getToken(pToken, userResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	users, nextPageToken, rateLimit, err := b.client.ListAllUsers(ctx, client.PageOptions{
+		Page:    pageToken,
+		PerPage: pToken.Size,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, user := range users {
+		if b.excludeInactive && isInactiveExtension(user) {
+			continue
+		}
+
+		details, err := b.getExtensionDetails(ctx, user)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		userResource, err := parseIntoUserResource(details)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		userResources = append(userResources, userResource)
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return userResources, nextPageToken, rateLimitAnnotations(rateLimit), nil
+}
+
+/*
+getExtensionDetails requests the extension of the user by its ID, since the list of extensions lacks most of the attributes of the profile.
+The creation time is only part of the details, so the listed extensions that carry it are kept as they are.
+The listed extension is also kept if it was deleted in the meantime.
+*/
+func (b *userBuilder) getExtensionDetails(ctx context.Context, user client.Extension) (client.Extension, error) {
+	if user.CreationTime != "" {
+		return user, nil
+	}
+
+	extensionID := strconv.FormatInt(user.ID, 10)
+
+	details, err := b.client.GetExtensionDetails(ctx, extensionID)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			ctxzap.Extract(ctx).Debug("ringcentral-connector: user extension was deleted while syncing", zap.String("extension_id", extensionID))
+			return user, nil
+		}
+		return client.Extension{}, err
+	}
+
+	return *details, nil
+}
+
+/*
+listScimUsers returns the users read from the SCIM API. The ID of a SCIM user is the ID of its extension, so the grants, the provisioning
+and the deprovisioning work the same way on both sync modes. The 'active' flag of SCIM doesn't tell inactive extensions apart
+from disabled ones, so the inactive extensions aren't excluded on this mode.
+*/
+func (b *userBuilder) listScimUsers(ctx context.Context, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var userResources []*v2.Resource
+
 	bag, pageToken, err := getToken(pToken, userResourceType)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	users, nextPageToken, err := b.client.ListAllUsers(ctx, client.PageOptions{
+	users, nextPageToken, rateLimit, err := b.client.ListScimUsers(ctx, client.PageOptions{
 		Page:    pageToken,
 		PerPage: pToken.Size,
 	})
@@ -44,7 +150,7 @@ func (b *userBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagina
 	}
 
 	for _, user := range users {
-		userResource, err := parseIntoUserResource(user)
+		userResource, err := parseIntoScimUserResource(user)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -57,7 +163,7 @@ func (b *userBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagina
 		return nil, "", nil, err
 	}
 
-	return userResources, nextPageToken, nil, nil
+	return userResources, nextPageToken, rateLimitAnnotations(rateLimit), nil
 }
 
 // Entitlements always returns an empty slice for users.
@@ -70,7 +176,7 @@ func (b *userBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *paginat
 func (b *userBuilder) Grants(ctx context.Context, userResource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	var roleGrants []*v2.Grant
 
-	userRoles, err := b.client.GetUserAssignedRoles(ctx, userResource)
+	userRoles, rateLimit, err := b.client.GetUserAssignedRoles(ctx, userResource)
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -82,31 +188,359 @@ func (b *userBuilder) Grants(ctx context.Context, userResource *v2.Resource, _ *
 				Resource:     userRole.Id,
 			},
 		}
-		roleGrants = append(roleGrants, grant.NewGrant(roleResource, rolePermissionName, userResource))
+
+		// Roles restricted to sites are granted through the entitlement of each site, instead of the account wide one.
+		if userRole.SiteRestricted && len(userRole.Sites) > 0 {
+			for _, site := range userRole.Sites {
+				roleGrants = append(roleGrants, grant.NewGrant(roleResource, siteRoleEntitlementName(site.ID), userResource, roleGrantOptions(userRole, &site)...))
+			}
+			continue
+		}
+
+		roleGrants = append(roleGrants, grant.NewGrant(roleResource, rolePermissionName, userResource, roleGrantOptions(userRole, nil)...))
+	}
+
+	return roleGrants, "", rateLimitAnnotations(rateLimit), nil
+}
+
+// CreateAccountCapabilityDetails reports that the users are created without a password, since the platform sends them a welcome email to set it up.
+func (b *userBuilder) CreateAccountCapabilityDetails(_ context.Context) (*v2.CredentialDetailsAccountProvisioning, annotations.Annotations, error) {
+	return &v2.CredentialDetailsAccountProvisioning{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+	}, nil, nil
+}
+
+/*
+CreateAccount creates the extension of a new user, as described by the account creation schema of the connector.
+The platform sends the welcome email to the user, who sets up their own password, so no credentials are returned.
+*/
+func (b *userBuilder) CreateAccount(
+	ctx context.Context,
+	accountInfo *v2.AccountInfo,
+	_ *v2.CredentialOptions,
+) (connectorbuilder.CreateAccountResponse, []*v2.PlaintextData, annotations.Annotations, error) {
+	userResource, err := b.createUser(ctx, accountInfo)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return &v2.CreateAccountResponse_SuccessResult{
+		Resource:              userResource,
+		IsCreateAccountResult: true,
+	}, nil, nil, nil
+}
+
+// Create creates the extension of a user from the user trait of the resource, the same way CreateAccount does from the account info.
+func (b *userBuilder) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	userTrait, err := rs.GetUserTrait(resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accountInfo := &v2.AccountInfo{
+		Login:   userTrait.GetLogin(),
+		Profile: userTrait.GetProfile(),
+	}
+	for _, email := range userTrait.GetEmails() {
+		accountInfo.Emails = append(accountInfo.Emails, &v2.AccountInfo_Email{
+			Address:   email.GetAddress(),
+			IsPrimary: email.GetIsPrimary(),
+		})
+	}
+
+	userResource, err := b.createUser(ctx, accountInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return userResource, nil, nil
+}
+
+func (b *userBuilder) createUser(ctx context.Context, accountInfo *v2.AccountInfo) (*v2.Resource, error) {
+	l := ctxzap.Extract(ctx)
+
+	body, err := newExtensionCreateBody(accountInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	// The SCIM API can look a user up by the email they log in with, so retried creations return the user created by the first attempt.
+	// The ID of the SCIM user is the ID of its extension, which is requested so the resource is built the same way as for a new user.
+	if b.syncMode == UserSyncModeSCIM {
+		existingUser, err := b.client.FindScimUser(ctx, body.Contact.Email)
+		if err != nil {
+			return nil, err
+		}
+
+		if existingUser != nil {
+			l.Debug("ringcentral-connector: user already exists", zap.String("extension_id", existingUser.ID))
+
+			extension, err := b.client.GetExtension(ctx, existingUser.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			return parseIntoUserResource(*extension)
+		}
+	}
+
+	extension, err := b.client.CreateExtension(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+
+	l.Info("ringcentral-connector: created user extension",
+		zap.Int64("extension_id", extension.ID),
+		zap.String("extension_number", body.ExtensionNumber))
+
+	return parseIntoUserResource(*extension)
+}
+
+/*
+Delete deprovisions the user following the deprovisioning policy: its extension is either disabled, keeping its data and phone numbers,
+or deleted. The extension the connector authenticates as is never deprovisioned, nor the last enabled holder of a protected role.
+*/
+func (b *userBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	extensionID := resourceId.Resource
+
+	extension, err := b.client.GetExtension(ctx, extensionID)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			l.Debug("ringcentral-connector: user extension is already deleted", zap.String("extension_id", extensionID))
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if b.deprovisioningPolicy != DeprovisioningPolicyDelete && extension.Status == client.ExtensionStatusDisabled {
+		l.Debug("ringcentral-connector: user extension is already disabled", zap.String("extension_id", extensionID))
+		return nil, nil
+	}
+
+	checkDeprovision, err := b.revokeGuard.checkDeprovision(ctx, extensionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.deprovisioningPolicy == DeprovisioningPolicyDelete {
+		err = b.client.DeprovisionExtension(ctx, extensionID, checkDeprovision, func(ctx context.Context) error {
+			return deprovisioningError(b.client.DeleteExtension(ctx, extensionID, b.reclaimPhoneNumbers), "delete", extensionID)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		l.Info("ringcentral-connector: deleted user extension",
+			zap.String("extension_id", extensionID),
+			zap.Bool("reclaim_phone_numbers", b.reclaimPhoneNumbers))
+		return nil, nil
+	}
+
+	err = b.client.DeprovisionExtension(ctx, extensionID, checkDeprovision, func(ctx context.Context) error {
+		return deprovisioningError(b.client.UpdateExtensionStatus(ctx, extensionID, client.ExtensionStatusDisabled), "disable", extensionID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l.Info("ringcentral-connector: disabled user extension", zap.String("extension_id", extensionID))
+
+	return nil, nil
+}
+
+// deprovisioningError reports the permission the app is missing when the platform refuses to disable or delete the extension.
+func deprovisioningError(err error, action string, extensionID string) error {
+	if status.Code(err) != codes.PermissionDenied {
+		return err
+	}
+
+	return status.Errorf(
+		codes.PermissionDenied,
+		"ringcentral-connector: the app requires the %s permission to %s the extension with ID: '%s': %v",
+		scopeEditAccounts,
+		action,
+		extensionID,
+		err,
+	)
+}
+
+// newExtensionCreateBody builds the request that creates the extension of a user from the account info. The email is required.
+func newExtensionCreateBody(accountInfo *v2.AccountInfo) (client.ExtensionCreateBody, error) {
+	profile := accountInfo.GetProfile()
+
+	email := getAccountEmail(accountInfo)
+	if email == "" {
+		return client.ExtensionCreateBody{}, status.Error(codes.InvalidArgument, "ringcentral-connector: the email of the user is required")
+	}
+
+	firstName, _ := rs.GetProfileStringValue(profile, accountFirstNameField)
+	lastName, _ := rs.GetProfileStringValue(profile, accountLastNameField)
+	extensionNumber, _ := rs.GetProfileStringValue(profile, accountExtensionNumberField)
+
+	body := client.ExtensionCreateBody{
+		Contact: client.ExtensionContact{
+			FirstName: firstName,
+			LastName:  lastName,
+			Email:     email,
+		},
+		Type:             client.ExtensionTypeUser,
+		ExtensionNumber:  extensionNumber,
+		SendWelcomeEmail: true,
+	}
+
+	if siteID, ok := rs.GetProfileStringValue(profile, accountSiteIDField); ok && siteID != "" {
+		body.Site = &client.SiteReference{ID: siteID}
+	}
+
+	if roleID, ok := rs.GetProfileStringValue(profile, accountRoleIDField); ok && roleID != "" {
+		body.Roles = []client.RoleReference{{ID: roleID}}
+	}
+
+	return body, nil
+}
+
+// getAccountEmail returns the primary email of the account info, falling back to its first email, the email field of the profile and the login.
+func getAccountEmail(accountInfo *v2.AccountInfo) string {
+	for _, email := range accountInfo.GetEmails() {
+		if email.GetIsPrimary() && email.GetAddress() != "" {
+			return email.GetAddress()
+		}
+	}
+
+	for _, email := range accountInfo.GetEmails() {
+		if email.GetAddress() != "" {
+			return email.GetAddress()
+		}
+	}
+
+	if email, ok := rs.GetProfileStringValue(accountInfo.GetProfile(), accountEmailField); ok && email != "" {
+		return email
+	}
+
+	return accountInfo.GetLogin()
+}
+
+/*
+roleGrantOptions describes how the role was assigned on the metadata of its grant, so the roles assigned by the platform itself can be told apart
+from the ones assigned by a person. Auto-assigned roles are also marked as immutable, since the platform assigns them again once revoked.
+*/
+func roleGrantOptions(userRole client.UserRole, site *client.SiteReference) []grant.GrantOption {
+	metadata := map[string]interface{}{
+		"auto_assigned":   userRole.AutoAssigned,
+		"site_restricted": userRole.SiteRestricted,
+	}
+
+	if site != nil {
+		metadata["site_id"] = site.ID
+		metadata["site_name"] = site.Name
+	}
+
+	options := []grant.GrantOption{
+		grant.WithGrantMetadata(metadata),
+	}
+
+	if userRole.AutoAssigned {
+		options = append(options, grant.WithAnnotation(&v2.GrantImmutable{}))
+	}
+
+	return options
+}
+
+// isInactiveExtension reports whether the extension has never been set up by a person, either because nobody was assigned to it
+// or because the assigned user didn't activate it yet.
+// inactiveExtensionStatuses are the statuses of the extensions skipped by excludeInactive, which aren't assigned to a person yet.
+var inactiveExtensionStatuses = []string{client.ExtensionStatusUnassigned, client.ExtensionStatusNotActivated}
+
+func isInactiveExtension(extension client.Extension) bool {
+	return slices.Contains(inactiveExtensionStatuses, extension.Status)
+}
+
+/*
+listExcludedUserIDs returns the IDs of the users skipped by excludeInactive, so the grants of the sites and the call queues skip them too
+instead of pointing at users that aren't synced. It returns nil when the inactive users aren't excluded.
+*/
+func listExcludedUserIDs(ctx context.Context, c *client.RingCentralClient, excludeInactive bool) (map[string]bool, error) {
+	if !excludeInactive {
+		return nil, nil
+	}
+
+	extensionIDs, err := c.ListExtensionIDsByStatus(ctx, client.UserExtensionTypes, inactiveExtensionStatuses)
+	if err != nil {
+		return nil, err
+	}
+
+	excludedUserIDs := make(map[string]bool, len(extensionIDs))
+	for _, extensionID := range extensionIDs {
+		excludedUserIDs[extensionID] = true
 	}
 
-	return roleGrants, "", nil, nil
+	return excludedUserIDs, nil
+}
+
+// parseUserStatus maps the status of an Extension into the status of the User Trait.
+// The returned details are used to tell apart the platform states that share the same trait status.
+func parseUserStatus(status string) (v2.UserTrait_Status_Status, string) {
+	switch status {
+	case client.ExtensionStatusEnabled:
+		return v2.UserTrait_Status_STATUS_ENABLED, ""
+	case client.ExtensionStatusDisabled:
+		return v2.UserTrait_Status_STATUS_DISABLED, ""
+	case client.ExtensionStatusFrozen:
+		return v2.UserTrait_Status_STATUS_DISABLED, "frozen"
+	case client.ExtensionStatusNotActivated:
+		return v2.UserTrait_Status_STATUS_DISABLED, "not_activated"
+	case client.ExtensionStatusUnassigned:
+		return v2.UserTrait_Status_STATUS_DISABLED, "unassigned"
+	default:
+		return v2.UserTrait_Status_STATUS_UNSPECIFIED, status
+	}
 }
 
 // parseIntoUserResource - This function parses an Extension (users from RingCentral) into a User Resource.
+// The extension number is an alternate login of the user, since it's used to sign in to the phones.
 func parseIntoUserResource(extension client.Extension) (*v2.Resource, error) {
-	var userStatus = v2.UserTrait_Status_STATUS_ENABLED
+	userStatus, statusDetails := parseUserStatus(extension.Status)
 
 	profile := map[string]interface{}{
-		"user_id":    extension.ID,
-		"email":      extension.ContactInfo.Email,
-		"first_name": extension.ContactInfo.FirstName,
-		"last_name":  extension.ContactInfo.LastName,
-		"status":     extension.Status,
+		"user_id":          extension.ID,
+		"email":            extension.ContactInfo.Email,
+		"first_name":       extension.ContactInfo.FirstName,
+		"last_name":        extension.ContactInfo.LastName,
+		"status":           extension.Status,
+		"extension_number": extension.ExtensionNumber,
+		"job_title":        extension.ContactInfo.JobTitle,
+		"department":       extension.ContactInfo.Department,
+		"business_phone":   extension.ContactInfo.BusinessPhone,
+		"mobile_phone":     extension.ContactInfo.MobilePhone,
+		"hire_date":        extension.ContactInfo.HireDate,
+		"site_id":          extension.Site.ID,
+		"site_name":        extension.Site.Name,
+		"cost_center_id":   extension.CostCenter.ID,
+		"cost_center_name": extension.CostCenter.Name,
+		"language":         extension.RegionalSettings.Language.LocaleCode,
+		"created_at":       extension.CreationTime,
+	}
+
+	var loginAliases []string
+	if extension.ExtensionNumber != "" {
+		loginAliases = append(loginAliases, extension.ExtensionNumber)
 	}
 
 	userTraits := []rs.UserTraitOption{
 		rs.WithUserProfile(profile),
-		rs.WithStatus(userStatus),
-		rs.WithUserLogin(extension.ContactInfo.Email),
+		rs.WithDetailedStatus(userStatus, statusDetails),
+		rs.WithUserLogin(extension.ContactInfo.Email, loginAliases...),
 		rs.WithEmail(extension.ContactInfo.Email, true),
 	}
 
+	if createdAt, err := time.Parse(time.RFC3339, extension.CreationTime); err == nil {
+		userTraits = append(userTraits, rs.WithCreatedAt(createdAt))
+	}
+
 	displayName := extension.Name
 	if displayName == "" {
 		displayName = extension.ContactInfo.Email
@@ -125,9 +559,94 @@ func parseIntoUserResource(extension client.Extension) (*v2.Resource, error) {
 	return ret, nil
 }
 
-func newUserBuilder(c *client.RingCentralClient) *userBuilder {
+// parseIntoScimUserResource parses a SCIM user into a User Resource, along with the attributes of the enterprise extension of SCIM.
+func parseIntoScimUserResource(user client.ScimUser) (*v2.Resource, error) {
+	var email string
+	for _, userEmail := range user.Emails {
+		if email == "" || userEmail.Primary {
+			email = userEmail.Value
+		}
+	}
+
+	profile := map[string]interface{}{
+		"user_id":         user.ID,
+		"external_id":     user.ExternalID,
+		"user_name":       user.UserName,
+		"email":           email,
+		"first_name":      user.Name.GivenName,
+		"last_name":       user.Name.FamilyName,
+		"active":          user.Active,
+		"title":           user.Title,
+		"department":      user.Enterprise.Department,
+		"employee_number": user.Enterprise.EmployeeNumber,
+		"manager_id":      user.Enterprise.Manager.Value,
+		"manager_name":    user.Enterprise.Manager.DisplayName,
+	}
+
+	for _, phoneNumber := range user.PhoneNumbers {
+		switch phoneNumber.Type {
+		case "work":
+			profile["business_phone"] = phoneNumber.Value
+		case "mobile":
+			profile["mobile_phone"] = phoneNumber.Value
+		}
+	}
+
+	userStatus := v2.UserTrait_Status_STATUS_DISABLED
+	if user.Active {
+		userStatus = v2.UserTrait_Status_STATUS_ENABLED
+	}
+
+	userTraits := []rs.UserTraitOption{
+		rs.WithUserProfile(profile),
+		rs.WithStatus(userStatus),
+		rs.WithUserLogin(user.UserName),
+		rs.WithStructuredName(&v2.UserTrait_StructuredName{
+			GivenName:  user.Name.GivenName,
+			FamilyName: user.Name.FamilyName,
+		}),
+	}
+
+	for _, userEmail := range user.Emails {
+		userTraits = append(userTraits, rs.WithEmail(userEmail.Value, userEmail.Value == email))
+	}
+
+	displayName := user.Name.Formatted
+	if displayName == "" {
+		displayName = strings.TrimSpace(user.Name.GivenName + " " + user.Name.FamilyName)
+	}
+	if displayName == "" {
+		displayName = user.UserName
+	}
+
+	ret, err := rs.NewUserResource(
+		displayName,
+		userResourceType,
+		user.ID,
+		userTraits,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func newUserBuilder(
+	c *client.RingCentralClient,
+	excludeInactive bool,
+	syncMode string,
+	guard *revokeGuard,
+	deprovisioningPolicy string,
+	reclaimPhoneNumbers bool,
+) *userBuilder {
 	return &userBuilder{
-		resourceType: userResourceType,
-		client:       c,
+		resourceType:         userResourceType,
+		client:               c,
+		excludeInactive:      excludeInactive,
+		syncMode:             syncMode,
+		revokeGuard:          guard,
+		deprovisioningPolicy: deprovisioningPolicy,
+		reclaimPhoneNumbers:  reclaimPhoneNumbers,
 	}
 }
@@ -0,0 +1,628 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-ringcentral/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// TestUserBuilder_CreateAccount tests that the extension of a new user is created from the account info, and returned as a user resource.
+func TestUserBuilder_CreateAccount(t *testing.T) {
+	var created []client.ExtensionCreateBody
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/restapi/v1.0/account/~/extension" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var body client.ExtensionCreateBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		created = append(created, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(client.Extension{
+			ID:          42,
+			Name:        body.Contact.FirstName + " " + body.Contact.LastName,
+			Type:        body.Type,
+			Status:      client.ExtensionStatusNotActivated,
+			ContactInfo: body.Contact,
+		})
+	}))
+	defer server.Close()
+
+	c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+	require.NoError(t, err)
+
+	b := newUserBuilder(c, false, UserSyncModeExtension, newRevokeGuard(c, DefaultProtectedRoles, false), DeprovisioningPolicyDisable, false)
+
+	profile, err := structpb.NewStruct(map[string]interface{}{
+		accountFirstNameField:       "Jane",
+		accountLastNameField:        "Doe",
+		accountExtensionNumberField: "204",
+		accountSiteIDField:          "berlin",
+		accountRoleIDField:          "1",
+	})
+	require.NoError(t, err)
+
+	accountInfo := &v2.AccountInfo{
+		Emails: []*v2.AccountInfo_Email{
+			{Address: "jane.doe@personal.example.com"},
+			{Address: "jane.doe@example.com", IsPrimary: true},
+		},
+		Profile: profile,
+	}
+
+	result, plaintexts, _, err := b.CreateAccount(context.Background(), accountInfo, nil)
+	require.NoError(t, err)
+	assert.Empty(t, plaintexts)
+
+	success, ok := result.(*v2.CreateAccountResponse_SuccessResult)
+	require.True(t, ok)
+	assert.True(t, success.IsCreateAccountResult)
+	assert.Equal(t, "42", success.Resource.Id.Resource)
+	assert.Equal(t, "Jane Doe", success.Resource.DisplayName)
+
+	require.Len(t, created, 1)
+	assert.Equal(t, client.ExtensionCreateBody{
+		Contact: client.ExtensionContact{
+			FirstName: "Jane",
+			LastName:  "Doe",
+			Email:     "jane.doe@example.com",
+		},
+		Type:             client.ExtensionTypeUser,
+		ExtensionNumber:  "204",
+		Site:             &client.SiteReference{ID: "berlin"},
+		Roles:            []client.RoleReference{{ID: "1"}},
+		SendWelcomeEmail: true,
+	}, created[0])
+
+	t.Run("without email", func(t *testing.T) {
+		_, _, _, err := b.CreateAccount(context.Background(), &v2.AccountInfo{Profile: profile}, nil)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Len(t, created, 1)
+	})
+}
+
+/*
+TestUserBuilder_Delete tests the deprovisioning policies. The connector authenticates as the extension 1, the Super Admin role (ID 1)
+is held by the extensions 1 and 2, and the extension 3 is the last holder of the Compliance role (ID 2), which is protected.
+The extension 4 holds no protected role, and the extension 5 is already disabled. The writes are refused when the app is denied.
+*/
+func TestUserBuilder_Delete(t *testing.T) {
+	responses := map[string]string{
+		"/restapi/v1.0/account/~/extension/~":               `{"id":1,"status":"Enabled"}`,
+		"/restapi/v1.0/account/~/extension":                 `{"records":[{"id":1,"status":"Enabled"},{"id":2,"status":"Enabled"},{"id":3,"status":"Enabled"}]}`,
+		"/restapi/v1.0/account/~/extension/1":               `{"id":1,"status":"Enabled"}`,
+		"/restapi/v1.0/account/~/extension/2":               `{"id":2,"status":"Enabled"}`,
+		"/restapi/v1.0/account/~/extension/3":               `{"id":3,"status":"Enabled"}`,
+		"/restapi/v1.0/account/~/extension/4":               `{"id":4,"status":"Enabled"}`,
+		"/restapi/v1.0/account/~/extension/5":               `{"id":5,"status":"Disabled"}`,
+		"/restapi/v1.0/account/~/extension/1/assigned-role": `{"records":[{"id":"1"}]}`,
+		"/restapi/v1.0/account/~/extension/2/assigned-role": `{"records":[{"id":"1"}]}`,
+		"/restapi/v1.0/account/~/extension/3/assigned-role": `{"records":[{"id":"2"}]}`,
+		"/restapi/v1.0/account/~/extension/4/assigned-role": `{"records":[{"id":"3"},{"id":"2","siteRestricted":true,"sites":[{"id":"berlin"}]}]}`,
+		"/restapi/v1.0/account/~/extension/5/assigned-role": `{"records":[{"id":"3"}]}`,
+		"/restapi/v1.0/account/~/user-role/1":               `{"id":"1","displayName":"Super Admin"}`,
+		"/restapi/v1.0/account/~/user-role/2":               `{"id":"2","displayName":"Compliance"}`,
+		"/restapi/v1.0/account/~/user-role/3":               `{"id":"3","displayName":"Standard"}`,
+	}
+
+	tests := []struct {
+		name        string
+		policy      string
+		reclaim     bool
+		allowUnsafe bool
+		denied      bool
+		extensionID string
+		code        codes.Code
+		write       string
+	}{
+		{name: "disable", policy: DeprovisioningPolicyDisable, extensionID: "4", write: `PUT /restapi/v1.0/account/~/extension/4 {"status":"Disabled"}`},
+		{name: "disable a disabled user", policy: DeprovisioningPolicyDisable, extensionID: "5"},
+		{name: "delete", policy: DeprovisioningPolicyDelete, extensionID: "4",
+			write: "DELETE /restapi/v1.0/account/~/extension/4?savePhoneLines=false&savePhoneNumbers=false"},
+		{name: "delete reclaiming the phone numbers", policy: DeprovisioningPolicyDelete, reclaim: true, extensionID: "5",
+			write: "DELETE /restapi/v1.0/account/~/extension/5?savePhoneLines=true&savePhoneNumbers=true"},
+		{name: "delete a missing user", policy: DeprovisioningPolicyDelete, extensionID: "6"},
+		{name: "protected role with other holders", policy: DeprovisioningPolicyDisable, extensionID: "2",
+			write: `PUT /restapi/v1.0/account/~/extension/2 {"status":"Disabled"}`},
+		{name: "the connector", policy: DeprovisioningPolicyDisable, allowUnsafe: true, extensionID: "1", code: codes.FailedPrecondition},
+		{name: "last holder of a protected role", policy: DeprovisioningPolicyDelete, extensionID: "3", code: codes.FailedPrecondition},
+		{name: "last holder of a protected role with unsafe revokes allowed", policy: DeprovisioningPolicyDisable, allowUnsafe: true, extensionID: "3",
+			write: `PUT /restapi/v1.0/account/~/extension/3 {"status":"Disabled"}`},
+		{name: "disable without permission", policy: DeprovisioningPolicyDisable, denied: true, extensionID: "4", code: codes.PermissionDenied,
+			write: `PUT /restapi/v1.0/account/~/extension/4 {"status":"Disabled"}`},
+		{name: "delete without permission", policy: DeprovisioningPolicyDelete, denied: true, extensionID: "4", code: codes.PermissionDenied,
+			write: "DELETE /restapi/v1.0/account/~/extension/4?savePhoneLines=false&savePhoneNumbers=false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var writes []string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodPut:
+					body, _ := io.ReadAll(r.Body)
+					writes = append(writes, r.Method+" "+r.URL.Path+" "+strings.TrimSpace(string(body)))
+					if tt.denied {
+						w.WriteHeader(http.StatusForbidden)
+						_, _ = w.Write([]byte(`{"errorCode":"InsufficientPermissions","message":"In order to call this API endpoint, application needs to have [EditAccounts] permission"}`))
+						return
+					}
+					w.Header().Set("Content-Type", "application/json")
+					_, _ = w.Write([]byte(`{}`))
+					return
+				case http.MethodDelete:
+					writes = append(writes, r.Method+" "+r.URL.String())
+					if tt.denied {
+						w.WriteHeader(http.StatusForbidden)
+						_, _ = w.Write([]byte(`{"errorCode":"CMN-408","message":"In order to call this API endpoint, application needs to have [EditAccounts] permission"}`))
+						return
+					}
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+
+				response, ok := responses[r.URL.Path]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(response))
+			}))
+			defer server.Close()
+
+			c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+			require.NoError(t, err)
+
+			guard := newRevokeGuard(c, []string{"Super Admin", "2"}, tt.allowUnsafe)
+			b := newUserBuilder(c, false, UserSyncModeExtension, guard, tt.policy, tt.reclaim)
+
+			_, err = b.Delete(context.Background(), newUserResourceID(tt.extensionID))
+			assert.Equal(t, tt.code, status.Code(err))
+			if tt.denied {
+				assert.ErrorContains(t, err, scopeEditAccounts)
+			}
+
+			if tt.write == "" {
+				assert.Empty(t, writes)
+				return
+			}
+			assert.Equal(t, []string{tt.write}, writes)
+		})
+	}
+}
+
+// TestUserBuilder_DeleteProtected tests that the last holder of a protected role is looked up when the deprovisioning is written, not when it's requested.
+func TestUserBuilder_DeleteProtected(t *testing.T) {
+	superAdmin := &v2.Resource{Id: &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "1"}, DisplayName: "Super Admin"}
+
+	tests := []struct {
+		name   string
+		policy string
+	}{
+		{name: "concurrent disables of the last holders", policy: DeprovisioningPolicyDisable},
+		{name: "concurrent deletes of the last holders", policy: DeprovisioningPolicyDelete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeRoleHolders("2", "3")
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+			require.NoError(t, err)
+
+			// The connector itself doesn't count as a holder, since it must not be the only one left with the role.
+			fake.disable("1")
+
+			b := newUserBuilder(c, false, UserSyncModeExtension, newRevokeGuard(c, DefaultProtectedRoles, false), tt.policy, false)
+
+			var wg sync.WaitGroup
+			errs := make([]error, 2)
+			for i, extensionID := range []string{"2", "3"} {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, errs[i] = b.Delete(context.Background(), newUserResourceID(extensionID))
+				}()
+			}
+			wg.Wait()
+
+			codesReturned := []codes.Code{status.Code(errs[0]), status.Code(errs[1])}
+			assert.ElementsMatch(t, []codes.Code{codes.OK, codes.FailedPrecondition}, codesReturned)
+
+			hasOtherHolder, err := c.HasOtherRoleHolder(context.Background(), "1", "1")
+			require.NoError(t, err)
+			assert.True(t, hasOtherHolder)
+		})
+	}
+
+	t.Run("deprovisioning alongside a revoke", func(t *testing.T) {
+		fake := newFakeRoleHolders("2", "3")
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+		require.NoError(t, err)
+
+		fake.disable("1")
+
+		guard := newRevokeGuard(c, DefaultProtectedRoles, false)
+		users := newUserBuilder(c, false, UserSyncModeExtension, guard, DeprovisioningPolicyDisable, false)
+		roles := newRoleBuilder(c, guard)
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, errs[0] = users.Delete(context.Background(), newUserResourceID("2"))
+		}()
+		go func() {
+			defer wg.Done()
+			_, errs[1] = roles.Revoke(context.Background(), &v2.Grant{
+				Entitlement: &v2.Entitlement{Id: entitlement.NewEntitlementID(superAdmin, rolePermissionName), Resource: superAdmin},
+				Principal:   &v2.Resource{Id: newUserResourceID("3")},
+			})
+		}()
+		wg.Wait()
+
+		codesReturned := []codes.Code{status.Code(errs[0]), status.Code(errs[1])}
+		assert.ElementsMatch(t, []codes.Code{codes.OK, codes.FailedPrecondition}, codesReturned)
+
+		hasOtherHolder, err := c.HasOtherRoleHolder(context.Background(), "1", "1")
+		require.NoError(t, err)
+		assert.True(t, hasOtherHolder)
+	})
+}
+
+// TestUserBuilder_ListScim tests that the users read from the SCIM API carry the 'active' flag and the attributes of the enterprise extension.
+func TestUserBuilder_ListScim(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/scim/v2/Users" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"totalResults":2,"Resources":[
+			{"id":"42","externalId":"E-42","userName":"jane.doe@example.com","active":true,"title":"Engineer",
+			 "name":{"givenName":"Jane","familyName":"Doe"},
+			 "emails":[{"value":"jane@personal.example.com","type":"other"},{"value":"jane.doe@example.com","type":"work","primary":true}],
+			 "phoneNumbers":[{"value":"+15550100","type":"work"},{"value":"+15550101","type":"mobile"}],
+			 "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"department":"R&D","employeeNumber":"1042","manager":{"value":"7"}}},
+			{"id":"43","userName":"john.doe@example.com","active":false}
+		]}`))
+	}))
+	defer server.Close()
+
+	c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+	require.NoError(t, err)
+
+	b := newUserBuilder(c, false, UserSyncModeSCIM, newRevokeGuard(c, DefaultProtectedRoles, false), DeprovisioningPolicyDisable, false)
+
+	resources, _, _, err := b.List(context.Background(), nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+
+	jane, err := rs.GetUserTrait(resources[0])
+	require.NoError(t, err)
+	assert.Equal(t, "42", resources[0].Id.Resource)
+	assert.Equal(t, "Jane Doe", resources[0].DisplayName)
+	assert.Equal(t, "jane.doe@example.com", jane.GetLogin())
+	assert.Equal(t, v2.UserTrait_Status_STATUS_ENABLED, jane.GetStatus().GetStatus())
+
+	profile := jane.GetProfile().AsMap()
+	assert.Equal(t, "jane.doe@example.com", profile["email"])
+	assert.Equal(t, "E-42", profile["external_id"])
+	assert.Equal(t, "R&D", profile["department"])
+	assert.Equal(t, "1042", profile["employee_number"])
+	assert.Equal(t, "7", profile["manager_id"])
+	assert.Equal(t, "+15550100", profile["business_phone"])
+	assert.Equal(t, "+15550101", profile["mobile_phone"])
+
+	john, err := rs.GetUserTrait(resources[1])
+	require.NoError(t, err)
+	assert.Equal(t, "john.doe@example.com", resources[1].DisplayName)
+	assert.Equal(t, v2.UserTrait_Status_STATUS_DISABLED, john.GetStatus().GetStatus())
+}
+
+// TestUserBuilder_CreateAccountScim tests that, on the SCIM sync mode, the users that already exist are returned instead of created again.
+func TestUserBuilder_CreateAccountScim(t *testing.T) {
+	var created int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/scim/v2/Users":
+			assert.Equal(t, `userName eq "jane.doe@example.com"`, r.URL.Query().Get("filter"))
+			_, _ = w.Write([]byte(`{"totalResults":1,"Resources":[{"id":"42","userName":"jane.doe@example.com","active":true}]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/restapi/v1.0/account/~/extension/42":
+			_, _ = w.Write([]byte(`{"id":42,"extensionNumber":"204","name":"Jane Doe","type":"User","status":"Enabled",` +
+				`"contact":{"firstName":"Jane","lastName":"Doe","email":"jane.doe@example.com"}}`))
+		case r.Method == http.MethodPost:
+			created++
+			_, _ = w.Write([]byte(`{"id":43}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+	require.NoError(t, err)
+
+	b := newUserBuilder(c, false, UserSyncModeSCIM, newRevokeGuard(c, DefaultProtectedRoles, false), DeprovisioningPolicyDisable, false)
+
+	result, _, _, err := b.CreateAccount(context.Background(), &v2.AccountInfo{Login: "jane.doe@example.com"}, nil)
+	require.NoError(t, err)
+
+	success, ok := result.(*v2.CreateAccountResponse_SuccessResult)
+	require.True(t, ok)
+	assert.Equal(t, "42", success.Resource.Id.Resource)
+	assert.Zero(t, created)
+
+	// The existing user is built from its extension, like the users created by the connector.
+	userTrait, err := rs.GetUserTrait(success.Resource)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"204"}, userTrait.GetLoginAliases())
+	assert.Equal(t, "204", userTrait.GetProfile().AsMap()["extension_number"])
+}
+
+// TestUserBuilder_ListExtensionDetails tests that the profile of the users is built from the details of each extension.
+func TestUserBuilder_ListExtensionDetails(t *testing.T) {
+	responses := map[string]string{
+		"/restapi/v1.0/account/~/extension": `{"records":[{"id":1,"name":"Jane Doe","status":"Enabled"},{"id":2,"name":"John Doe","status":"Enabled"}]}`,
+		"/restapi/v1.0/account/~/extension/1": `{"id":1,"extensionNumber":"101","name":"Jane Doe","type":"User","status":"Enabled",
+			"contact":{"firstName":"Jane","lastName":"Doe","email":"jane.doe@example.com","jobTitle":"Engineer","department":"R&D",
+				"businessPhone":"+15550100","mobilePhone":"+15550101","hireDate":"2021-03-01"},
+			"site":{"id":"berlin","name":"Berlin"},"costCenter":{"id":"7","name":"Engineering"},
+			"regionalSettings":{"language":{"id":"1033","name":"English (United States)","localeCode":"en-US"}},
+			"creationTime":"2021-02-15T09:30:00Z"}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+	require.NoError(t, err)
+
+	b := newUserBuilder(c, false, UserSyncModeExtension, newRevokeGuard(c, DefaultProtectedRoles, false), DeprovisioningPolicyDisable, false)
+
+	resources, _, _, err := b.List(context.Background(), nil, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+
+	jane, err := rs.GetUserTrait(resources[0])
+	require.NoError(t, err)
+	assert.Equal(t, "jane.doe@example.com", jane.GetLogin())
+	assert.Equal(t, []string{"101"}, jane.GetLoginAliases())
+	assert.Equal(t, time.Date(2021, 2, 15, 9, 30, 0, 0, time.UTC), jane.GetCreatedAt().AsTime())
+
+	profile := jane.GetProfile().AsMap()
+	assert.Equal(t, "101", profile["extension_number"])
+	assert.Equal(t, "Engineer", profile["job_title"])
+	assert.Equal(t, "R&D", profile["department"])
+	assert.Equal(t, "+15550100", profile["business_phone"])
+	assert.Equal(t, "+15550101", profile["mobile_phone"])
+	assert.Equal(t, "2021-03-01", profile["hire_date"])
+	assert.Equal(t, "berlin", profile["site_id"])
+	assert.Equal(t, "Berlin", profile["site_name"])
+	assert.Equal(t, "7", profile["cost_center_id"])
+	assert.Equal(t, "en-US", profile["language"])
+
+	// The extension 2 is deleted between the list and the request of its details, so the listed summary is kept.
+	assert.Equal(t, "John Doe", resources[1].DisplayName)
+}
+
+// TestUserBuilder_ListExtensionDetailsRequests tests that the details are only requested for the listed extensions that lack them, and only once.
+func TestUserBuilder_ListExtensionDetailsRequests(t *testing.T) {
+	responses := map[string]string{
+		"/restapi/v1.0/account/~/extension": `{"records":[{"id":1,"status":"Enabled"},{"id":2,"status":"Enabled"},` +
+			`{"id":3,"status":"Enabled","creationTime":"2021-02-15T09:30:00Z"}]}`,
+		"/restapi/v1.0/account/~/extension/1": `{"id":1,"status":"Enabled","creationTime":"2021-02-15T09:30:00Z"}`,
+		"/restapi/v1.0/account/~/extension/2": `{"id":2,"status":"Enabled","creationTime":"2021-02-15T09:30:00Z"}`,
+		"/restapi/v1.0/account/~/extension/3": `{"id":3,"status":"Enabled","creationTime":"2021-02-15T09:30:00Z"}`,
+	}
+
+	var mtx sync.Mutex
+	requests := map[string]int{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		requests[r.URL.Path]++
+		mtx.Unlock()
+
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+	require.NoError(t, err)
+
+	b := newUserBuilder(c, false, UserSyncModeExtension, newRevokeGuard(c, DefaultProtectedRoles, false), DeprovisioningPolicyDisable, false)
+
+	// The page is listed twice, like when it's retried, and the second time is served by the response cache.
+	for range 2 {
+		resources, _, _, err := b.List(context.Background(), nil, &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, resources, 3)
+	}
+
+	assert.Equal(t, map[string]int{
+		"/restapi/v1.0/account/~/extension":   1,
+		"/restapi/v1.0/account/~/extension/1": 1,
+		"/restapi/v1.0/account/~/extension/2": 1,
+	}, requests)
+}
+
+// TestParseUserStatus tests the mapping of the statuses of the extensions into the statuses of the user trait.
+func TestParseUserStatus(t *testing.T) {
+	tests := []struct {
+		status  string
+		want    v2.UserTrait_Status_Status
+		details string
+	}{
+		{status: client.ExtensionStatusEnabled, want: v2.UserTrait_Status_STATUS_ENABLED},
+		{status: client.ExtensionStatusDisabled, want: v2.UserTrait_Status_STATUS_DISABLED},
+		{status: client.ExtensionStatusFrozen, want: v2.UserTrait_Status_STATUS_DISABLED, details: "frozen"},
+		{status: client.ExtensionStatusNotActivated, want: v2.UserTrait_Status_STATUS_DISABLED, details: "not_activated"},
+		{status: client.ExtensionStatusUnassigned, want: v2.UserTrait_Status_STATUS_DISABLED, details: "unassigned"},
+		{status: "Migrated", want: v2.UserTrait_Status_STATUS_UNSPECIFIED, details: "Migrated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			got, details := parseUserStatus(tt.status)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.details, details)
+		})
+	}
+}
+
+/*
+TestExcludeInactiveUsers tests that the inactive users are skipped by the user list and by the grants of the sites and the call queues
+when they're excluded, so no grant points at a user that isn't synced. The extension 2 is unassigned and the extension 3 isn't activated yet.
+*/
+func TestExcludeInactiveUsers(t *testing.T) {
+	extensions := []client.Extension{
+		{ID: 1, Type: client.ExtensionTypeUser, Status: client.ExtensionStatusEnabled},
+		{ID: 2, Type: client.ExtensionTypeUser, Status: client.ExtensionStatusUnassigned},
+		{ID: 3, Type: client.ExtensionTypeUser, Status: client.ExtensionStatusNotActivated},
+		{ID: 4, Type: client.ExtensionTypeUser, Status: client.ExtensionStatusDisabled},
+	}
+	responses := map[string]string{
+		"/restapi/v1.0/account/~/sites/10/members":        `{"records":[{"id":"1","type":"User"},{"id":"2","type":"User"},{"id":"4","type":"User"}]}`,
+		"/restapi/v1.0/account/~/call-queues/20/members":  `{"records":[{"id":"1"},{"id":"3"}],"paging":{"page":1,"totalPages":1}}`,
+		"/restapi/v1.0/account/~/call-queues/20/managers": `{"records":[{"extension":{"id":"2"},"permission":"FullAccess"},{"extension":{"id":"4"},"permission":"Members"}]}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == "/restapi/v1.0/account/~/extension" {
+			statuses := r.URL.Query()["status"]
+
+			var response client.ExtensionResponse
+			for _, extension := range extensions {
+				if len(statuses) == 0 || slices.Contains(statuses, extension.Status) {
+					extension.CreationTime = "2021-02-15T09:30:00Z"
+					response.Records = append(response.Records, extension)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	c, err := client.New(context.Background(), client.WithBaseURL(server.URL), client.WithAccessToken("token"))
+	require.NoError(t, err)
+
+	site := &v2.Resource{Id: &v2.ResourceId{ResourceType: siteResourceType.Id, Resource: "10"}}
+	callQueue := &v2.Resource{Id: &v2.ResourceId{ResourceType: callQueueResourceType.Id, Resource: "20"}}
+
+	tests := []struct {
+		name            string
+		excludeInactive bool
+		users           []string
+		siteMembers     []string
+		callQueueUsers  []string
+	}{
+		{name: "inactive users synced", users: []string{"1", "2", "3", "4"}, siteMembers: []string{"1", "2", "4"}, callQueueUsers: []string{"1", "3", "2", "4"}},
+		{name: "inactive users excluded", excludeInactive: true, users: []string{"1", "4"}, siteMembers: []string{"1", "4"}, callQueueUsers: []string{"1", "4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := newRevokeGuard(c, DefaultProtectedRoles, false)
+			users := newUserBuilder(c, tt.excludeInactive, UserSyncModeExtension, guard, DeprovisioningPolicyDisable, false)
+
+			resources, _, _, err := users.List(context.Background(), nil, &pagination.Token{})
+			require.NoError(t, err)
+			var userIDs []string
+			for _, resource := range resources {
+				userIDs = append(userIDs, resource.Id.Resource)
+			}
+			assert.Equal(t, tt.users, userIDs)
+
+			siteGrants, _, _, err := newSiteBuilder(c, tt.excludeInactive).Grants(context.Background(), site, &pagination.Token{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.siteMembers, grantPrincipalIDs(siteGrants))
+
+			var callQueueGrants []*v2.Grant
+			callQueues := newCallQueueBuilder(c, tt.excludeInactive)
+			pToken := &pagination.Token{}
+			for {
+				grants, nextPageToken, _, err := callQueues.Grants(context.Background(), callQueue, pToken)
+				require.NoError(t, err)
+				callQueueGrants = append(callQueueGrants, grants...)
+				if nextPageToken == "" {
+					break
+				}
+				pToken = &pagination.Token{Token: nextPageToken}
+			}
+			assert.Equal(t, tt.callQueueUsers, grantPrincipalIDs(callQueueGrants))
+		})
+	}
+}
+
+func grantPrincipalIDs(grants []*v2.Grant) []string {
+	var principalIDs []string
+	for _, g := range grants {
+		principalIDs = append(principalIDs, g.Principal.Id.Resource)
+	}
+
+	return principalIDs
+}